@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ZiplEix/crafteur/services"
+	"github.com/labstack/echo/v4"
+)
+
+type CrashController struct {
+	crashService *services.CrashService
+}
+
+func NewCrashController(cs *services.CrashService) *CrashController {
+	return &CrashController{crashService: cs}
+}
+
+// GET /api/servers/:id/crashes
+func (ctrl *CrashController) List(c echo.Context) error {
+	reports, err := ctrl.crashService.ListCrashes(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, reports)
+}
+
+// GET /api/servers/:id/crashes/content?file=crash-reports/crash-....txt
+func (ctrl *CrashController) Get(c echo.Context) error {
+	report, content, err := ctrl.crashService.GetCrash(c.Param("id"), c.QueryParam("file"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"report": report, "content": content})
+}
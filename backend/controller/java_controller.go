@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ZiplEix/crafteur/services"
+	"github.com/labstack/echo/v4"
+)
+
+type JavaController struct {
+	javaService   *services.JavaService
+	serverService *services.ServerService
+}
+
+func NewJavaController(js *services.JavaService, ss *services.ServerService) *JavaController {
+	return &JavaController{
+		javaService:   js,
+		serverService: ss,
+	}
+}
+
+// GET /api/java/runtimes
+func (ctrl *JavaController) ListRuntimes(c echo.Context) error {
+	runtimes, err := ctrl.javaService.ListRuntimes()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, runtimes)
+}
+
+// POST /api/java/runtimes
+// Either a multipart "archive" upload, or JSON {"path": "/opt/jdk-17.tar.gz"} for an archive already on the host
+func (ctrl *JavaController) InstallRuntime(c echo.Context) error {
+	if file, err := c.FormFile("archive"); err == nil {
+		rt, err := ctrl.javaService.InstallUpload(file)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusCreated, rt)
+	}
+
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := c.Bind(&req); err != nil || req.Path == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "archive file or path is required"})
+	}
+
+	rt, err := ctrl.javaService.InstallArchive(req.Path, req.Path)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, rt)
+}
+
+// PUT /api/servers/:id/java
+func (ctrl *JavaController) AssignRuntime(c echo.Context) error {
+	id := c.Param("id")
+	var req struct {
+		JavaVersion int `json:"java_version"`
+	}
+	if err := c.Bind(&req); err != nil || req.JavaVersion <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "java_version is required"})
+	}
+
+	rt, err := ctrl.serverService.SetJavaVersion(id, req.JavaVersion)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, rt)
+}
@@ -1,8 +1,14 @@
 package controller
 
 import (
+	"encoding/json"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ZiplEix/crafteur/minecraft"
 	"github.com/ZiplEix/crafteur/services"
 	"github.com/labstack/echo/v4"
 )
@@ -42,10 +48,140 @@ func (c *LogController) GetLogContent(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Filename is required"})
 	}
 
-	content, err := c.logService.ReadLogFile(serverID, filename)
+	// ?offset=&limit= (lines) or ?tail=N reads only part of the file
+	if ctx.QueryParam("offset") != "" || ctx.QueryParam("limit") != "" || ctx.QueryParam("tail") != "" {
+		offset, _ := strconv.Atoi(ctx.QueryParam("offset"))
+		limit, _ := strconv.Atoi(ctx.QueryParam("limit"))
+		tail, _ := strconv.Atoi(ctx.QueryParam("tail"))
+		if offset < 0 || limit < 0 || tail < 0 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "offset, limit and tail must not be negative"})
+		}
+		logRange, err := c.logService.ReadLogRange(serverID, filename, offset, limit, tail)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return ctx.JSON(http.StatusOK, logRange)
+	}
+
+	// ?format=structured&level=WARN,ERROR streams parsed entries of the whole file instead of
+	// the raw text, as newline-delimited JSON ended by {"done":true,"entries":N}
+	if ctx.QueryParam("format") == "structured" {
+		var levels []string
+		if level := ctx.QueryParam("level"); level != "" {
+			levels = strings.Split(level, ",")
+		}
+		return c.streamLogEntries(ctx, serverID, filename, levels)
+	}
+
+	content, truncated, err := c.logService.ReadLogFile(serverID, filename)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	setTruncated(ctx, truncated)
 
 	return ctx.String(http.StatusOK, content)
 }
+
+func (c *LogController) streamLogEntries(ctx echo.Context, serverID, filename string, levels []string) error {
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+
+	count, err := c.logService.StreamLogEntries(ctx.Request().Context(), serverID, filename, levels, func(entry minecraft.LogEntry) error {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	})
+
+	// Headers are sent, errors go in the final line
+	final := map[string]any{"done": true, "entries": count}
+	if err != nil {
+		final["error"] = err.Error()
+	}
+	encoder.Encode(final)
+	return nil
+}
+
+// setTruncated flags a response holding only the end of a big file, the whole file is read
+// with ?offset=&limit=
+func setTruncated(ctx echo.Context, truncated bool) {
+	if truncated {
+		ctx.Response().Header().Set("X-Log-Truncated", "true")
+	}
+}
+
+// GET /api/servers/:id/logs/search?q=&since=&until=&level=&context=&limit=&file=
+// Streams newline-delimited JSON: one object per match, then {"done":true,...} with the summary.
+func (c *LogController) Search(ctx echo.Context) error {
+	serverID := ctx.Param("id")
+
+	opts := services.LogSearchOptions{File: ctx.QueryParam("file")}
+	if q := ctx.QueryParam("q"); q != "" {
+		pattern, err := regexp.Compile(q)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid regex: " + err.Error()})
+		}
+		opts.Pattern = pattern
+	}
+	var err error
+	if opts.Since, err = parseLogDate(ctx.QueryParam("since")); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid since"})
+	}
+	if opts.Until, err = parseLogDate(ctx.QueryParam("until")); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid until"})
+	}
+	// A bare day as upper bound includes that whole day
+	if len(ctx.QueryParam("until")) == len("2006-01-02") {
+		opts.Until = opts.Until.Add(24*time.Hour - time.Second)
+	}
+	if level := ctx.QueryParam("level"); level != "" {
+		opts.Levels = strings.Split(level, ",")
+	}
+	opts.Context, _ = strconv.Atoi(ctx.QueryParam("context"))
+	opts.Context = max(0, min(opts.Context, 10))
+	opts.Limit, _ = strconv.Atoi(ctx.QueryParam("limit"))
+	opts.Limit = max(0, min(opts.Limit, 2000))
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+
+	summary, err := c.logService.SearchLogs(ctx.Request().Context(), serverID, opts, func(m services.LogMatch) error {
+		if err := encoder.Encode(m); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	})
+
+	// Headers are sent, errors go in the final line
+	final := map[string]any{"done": true}
+	if summary != nil {
+		final["matches"] = summary.Matches
+		final["files"] = summary.Files
+		final["truncated"] = summary.Truncated
+	}
+	if err != nil {
+		final["error"] = err.Error()
+	}
+	encoder.Encode(final)
+	return nil
+}
+
+// parseLogDate accepts RFC 3339, a bare day (2006-01-02, local time) or unix seconds
+func parseLogDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return day, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
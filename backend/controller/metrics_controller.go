@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/crafteur/services"
+	"github.com/labstack/echo/v4"
+)
+
+type MetricsController struct {
+	metricsService  *services.MetricsService
+	exporterService *services.ExporterService
+	scrapeToken     string // Required by the Prometheus endpoint, which is disabled when empty
+}
+
+func NewMetricsController(ms *services.MetricsService, es *services.ExporterService, scrapeToken string) *MetricsController {
+	return &MetricsController{
+		metricsService:  ms,
+		exporterService: es,
+		scrapeToken:     scrapeToken,
+	}
+}
+
+// GET /api/servers/:id/metrics?from=&to=&step=
+// from/to are unix seconds or RFC3339 (default: the last hour), step is seconds or a duration like "5m"
+func (ctrl *MetricsController) Get(c echo.Context) error {
+	from, err := parseTime(c.QueryParam("from"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from"})
+	}
+	to, err := parseTime(c.QueryParam("to"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to"})
+	}
+	step, err := parseStep(c.QueryParam("step"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid step"})
+	}
+
+	series, err := ctrl.metricsService.Query(c.Param("id"), from, to, step)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, series)
+}
+
+func parseStep(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// GET /metrics, Prometheus text format.
+// The scrape token goes in "Authorization: Bearer <token>" or ?token=
+func (ctrl *MetricsController) Export(c echo.Context) error {
+	if ctrl.scrapeToken == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "metrics endpoint disabled, set METRICS_TOKEN"})
+	}
+	token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if token == "" {
+		token = c.QueryParam("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(ctrl.scrapeToken)) != 1 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid scrape token"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	return ctrl.exporterService.Write(c.Response())
+}
@@ -1,9 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/ZiplEix/crafteur/minecraft"
 	"github.com/ZiplEix/crafteur/services"
 	"github.com/labstack/echo/v4"
 )
@@ -47,6 +51,52 @@ func (c *PlayerController) GetBanned(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, bans)
 }
 
+// GET /api/servers/:id/players/playtime
+func (c *PlayerController) GetPlaytime(ctx echo.Context) error {
+	playtimes, err := c.playerService.GetPlaytimes(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, playtimes)
+}
+
+// GET /api/servers/:id/players/sessions?player=&from=&to=&limit=
+func (c *PlayerController) GetSessions(ctx echo.Context) error {
+	from, err := parseTime(ctx.QueryParam("from"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from"})
+	}
+	to, err := parseTime(ctx.QueryParam("to"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to"})
+	}
+	limit, _ := strconv.Atoi(ctx.QueryParam("limit"))
+
+	sessions, err := c.playerService.GetSessions(ctx.Param("id"), ctx.QueryParam("player"), from, to, limit)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, sessions)
+}
+
+// GET /api/servers/:id/players/activity?from=&to= (default: the last 30 days)
+func (c *PlayerController) GetActivity(ctx echo.Context) error {
+	from, err := parseTime(ctx.QueryParam("from"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from"})
+	}
+	to, err := parseTime(ctx.QueryParam("to"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to"})
+	}
+
+	activity, err := c.playerService.GetActivity(ctx.Param("id"), from, to)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, activity)
+}
+
 type PlayerActionRequest struct {
 	PlayerName string `json:"player"`
 	Action     string `json:"action"` // op, deop, ban, pardon, kick, whitelist_add, whitelist_remove
@@ -63,6 +113,12 @@ func (c *PlayerController) HandleAction(ctx echo.Context) error {
 	if req.PlayerName == "" || req.Action == "" {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing player or action"})
 	}
+	if err := services.CheckPlayerName(req.PlayerName); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := services.CheckReason(req.Reason); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	var cmd string
 	switch req.Action {
@@ -92,9 +148,210 @@ func (c *PlayerController) HandleAction(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown action"})
 	}
 
-	if err := c.serverService.SendCommand(serverID, cmd); err != nil {
+	response, err := c.serverService.SendRCON(serverID, cmd)
+	if errors.Is(err, minecraft.ErrRCONUnavailable) {
+		// No RCON (e.g. server started before it was enabled): fire and forget through stdin
+		if err := c.serverService.SendCommand(serverID, cmd); err != nil {
+			return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return ctx.JSON(http.StatusOK, map[string]string{"status": "sent", "command": cmd})
+	}
+	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok", "command": cmd})
+	if services.CommandRejected(response) {
+		return ctx.JSON(http.StatusUnprocessableEntity, map[string]string{"error": response, "command": cmd})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok", "command": cmd, "response": response})
+}
+
+// GET /api/servers/:id/players/profile?name=Steve or ?uuid=...
+func (c *PlayerController) GetProfile(ctx echo.Context) error {
+	name, uuid := ctx.QueryParam("name"), ctx.QueryParam("uuid")
+	if name == "" && uuid == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing name or uuid"})
+	}
+	profile, err := c.playerService.ResolveProfile(ctx.Param("id"), name, uuid)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, profile)
+}
+
+// GET /api/servers/:id/playerdata/:player?world= (name or UUID, default: the active world)
+func (c *PlayerController) GetPlayerData(ctx echo.Context) error {
+	data, err := c.playerService.GetPlayerData(ctx.Param("id"), ctx.Param("player"), ctx.QueryParam("world"))
+	if err != nil {
+		return playerDataError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, data)
+}
+
+// GET /api/servers/:id/playerdata/:player/stats?world=
+func (c *PlayerController) GetPlayerStats(ctx echo.Context) error {
+	stats, err := c.playerService.GetPlayerStats(ctx.Param("id"), ctx.Param("player"), ctx.QueryParam("world"))
+	if err != nil {
+		return playerDataError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, stats)
+}
+
+// GET /api/servers/:id/playerdata/:player/advancements?world=&recipes=true
+func (c *PlayerController) GetPlayerAdvancements(ctx echo.Context) error {
+	recipes := ctx.QueryParam("recipes") == "true"
+	advancements, err := c.playerService.GetPlayerAdvancements(ctx.Param("id"), ctx.Param("player"), ctx.QueryParam("world"), recipes)
+	if err != nil {
+		return playerDataError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, advancements)
+}
+
+func playerDataError(ctx echo.Context, err error) error {
+	if errors.Is(err, services.ErrNoPlayerData) {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return listError(ctx, err)
+}
+
+// listError maps the errors of list edits to HTTP statuses
+func listError(ctx echo.Context, err error) error {
+	var rejected *services.CommandRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return ctx.JSON(http.StatusUnprocessableEntity, map[string]string{"error": rejected.Response, "command": rejected.Command})
+	case errors.Is(err, services.ErrUnknownPlayer):
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrServerBusy), errors.Is(err, services.ErrOfflineOnly):
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
+
+type ListEntryRequest struct {
+	Name                string `json:"name"`
+	IP                  string `json:"ip"`
+	Level               int    `json:"level"`
+	BypassesPlayerLimit bool   `json:"bypassesPlayerLimit"`
+	Reason              string `json:"reason"`
+	Expires             string `json:"expires"` // RFC3339 or unix seconds, empty for a permanent ban
+}
+
+func (c *PlayerController) bindEntry(ctx echo.Context) (*ListEntryRequest, time.Time, error) {
+	var req ListEntryRequest
+	if err := ctx.Bind(&req); err != nil {
+		return nil, time.Time{}, err
+	}
+	expires, err := parseTime(req.Expires)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return &req, expires, nil
+}
+
+// GET /api/servers/:id/players/whitelist
+func (c *PlayerController) GetWhitelist(ctx echo.Context) error {
+	whitelist, err := c.playerService.GetWhitelist(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, whitelist)
+}
+
+// POST /api/servers/:id/players/whitelist {"name": "Steve"}
+func (c *PlayerController) AddToWhitelist(ctx echo.Context) error {
+	req, _, err := c.bindEntry(ctx)
+	if err != nil || req.Name == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing player name"})
+	}
+	entry, err := c.playerService.AddToWhitelist(ctx.Param("id"), req.Name)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, entry)
+}
+
+// DELETE /api/servers/:id/players/whitelist/:name
+func (c *PlayerController) RemoveFromWhitelist(ctx echo.Context) error {
+	if err := c.playerService.RemoveFromWhitelist(ctx.Param("id"), ctx.Param("name")); err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// POST /api/servers/:id/players/ops {"name": "Steve", "level": 4, "bypassesPlayerLimit": false}
+func (c *PlayerController) SetOp(ctx echo.Context) error {
+	req, _, err := c.bindEntry(ctx)
+	if err != nil || req.Name == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing player name"})
+	}
+	op, err := c.playerService.SetOp(ctx.Param("id"), minecraft.OpEntry{
+		Name:                req.Name,
+		Level:               req.Level,
+		BypassesPlayerLimit: req.BypassesPlayerLimit,
+	})
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, op)
+}
+
+// DELETE /api/servers/:id/players/ops/:name
+func (c *PlayerController) RemoveOp(ctx echo.Context) error {
+	if err := c.playerService.RemoveOp(ctx.Param("id"), ctx.Param("name")); err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// POST /api/servers/:id/players/banned {"name": "Steve", "reason": "...", "expires": "2025-01-01T00:00:00Z"}
+func (c *PlayerController) Ban(ctx echo.Context) error {
+	req, expires, err := c.bindEntry(ctx)
+	if err != nil || req.Name == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing player name or invalid expires"})
+	}
+	ban, err := c.playerService.BanPlayer(ctx.Param("id"), req.Name, req.Reason, expires)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, ban)
+}
+
+// DELETE /api/servers/:id/players/banned/:name
+func (c *PlayerController) Pardon(ctx echo.Context) error {
+	if err := c.playerService.PardonPlayer(ctx.Param("id"), ctx.Param("name")); err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// GET /api/servers/:id/players/banned-ips
+func (c *PlayerController) GetBannedIPs(ctx echo.Context) error {
+	bans, err := c.playerService.GetBannedIPs(ctx.Param("id"))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, bans)
+}
+
+// POST /api/servers/:id/players/banned-ips {"ip": "1.2.3.4", "reason": "...", "expires": ""}
+func (c *PlayerController) BanIP(ctx echo.Context) error {
+	req, expires, err := c.bindEntry(ctx)
+	if err != nil || req.IP == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing IP or invalid expires"})
+	}
+	ban, err := c.playerService.BanIP(ctx.Param("id"), req.IP, req.Reason, expires)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, ban)
+}
+
+// DELETE /api/servers/:id/players/banned-ips/:ip
+func (c *PlayerController) PardonIP(ctx echo.Context) error {
+	if err := c.playerService.PardonIP(ctx.Param("id"), ctx.Param("ip")); err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
 }
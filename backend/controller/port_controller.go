@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ZiplEix/crafteur/services"
+	"github.com/labstack/echo/v4"
+)
+
+type PortController struct {
+	portService *services.PortService
+}
+
+func NewPortController(ps *services.PortService) *PortController {
+	return &PortController{portService: ps}
+}
+
+// GET /api/meta/ports
+func (ctrl *PortController) List(c echo.Context) error {
+	allocations, err := ctrl.portService.ListAllocations()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, allocations)
+}
+
+// GET /api/meta/ports/suggest?from=25565
+func (ctrl *PortController) Suggest(c echo.Context) error {
+	from, _ := strconv.Atoi(c.QueryParam("from"))
+	port, err := ctrl.portService.SuggestPort(from)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int{"port": port})
+}
+
+// GET /api/servers/:id/ports
+func (ctrl *PortController) GetServerPorts(c echo.Context) error {
+	allocations, err := ctrl.portService.ServerAllocations(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, allocations)
+}
+
+// portError answers 409 with the suggested port on a port conflict, fallback otherwise
+func portError(c echo.Context, err error, fallback int) error {
+	if conflict, ok := services.IsPortConflict(err); ok {
+		return c.JSON(http.StatusConflict, map[string]any{
+			"error":          err.Error(),
+			"port":           conflict.Port,
+			"suggested_port": conflict.Suggested,
+		})
+	}
+	return c.JSON(fallback, map[string]string{"error": err.Error()})
+}
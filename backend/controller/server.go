@@ -1,8 +1,14 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ZiplEix/crafteur/core"
 	"github.com/ZiplEix/crafteur/minecraft"
@@ -70,7 +76,8 @@ func (ctrl *ServerController) Create(c echo.Context) error {
 	port, _ := strconv.Atoi(portStr)
 	ram, _ := strconv.Atoi(ramStr)
 
-	if port < 1024 || ram < 512 {
+	// An empty port lets the panel pick the next free one
+	if (portStr != "" && (port < 1024 || port > 65535)) || ram < 512 {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid port or insufficient RAM"})
 	}
 
@@ -109,12 +116,36 @@ func (ctrl *ServerController) Create(c echo.Context) error {
 
 	newServer, err := ctrl.service.CreateNewServer(name, sType, port, ram, version, file)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return portError(c, err, http.StatusInternalServerError)
 	}
 
 	return c.JSON(http.StatusCreated, newServer)
 }
 
+// PUT /api/servers/:id
+func (ctrl *ServerController) Update(c echo.Context) error {
+	id := c.Param("id")
+	var req core.ServerUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	}
+
+	cfg, err := ctrl.service.UpdateServer(id, &req)
+	if err != nil {
+		return portError(c, err, http.StatusBadRequest)
+	}
+	return c.JSON(http.StatusOK, cfg)
+}
+
+// GET /api/servers/:id/status
+func (ctrl *ServerController) Status(c echo.Context) error {
+	report, err := ctrl.service.GetLiveStatus(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
 // POST /api/servers/:id/start
 func (ctrl *ServerController) Start(c echo.Context) error {
 	id := c.Param("id")
@@ -124,13 +155,53 @@ func (ctrl *ServerController) Start(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "starting"})
 }
 
-// POST /api/servers/:id/stop
+// POST /api/servers/:id/stop?wait=true&timeout=30
 func (ctrl *ServerController) Stop(c echo.Context) error {
 	id := c.Param("id")
-	if err := ctrl.service.StopServer(id); err != nil {
+
+	if c.QueryParam("wait") != "true" {
+		if err := ctrl.service.StopServer(id); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"status": "stopping"})
+	}
+
+	timeout, err := parseTimeout(c.QueryParam("timeout"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := ctrl.service.StopServerAndWait(c.Request().Context(), id, timeout); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "stopped"})
+}
+
+// POST /api/servers/:id/restart?timeout=30
+func (ctrl *ServerController) Restart(c echo.Context) error {
+	id := c.Param("id")
+	timeout, err := parseTimeout(c.QueryParam("timeout"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// A client disconnecting must not leave the server stopped halfway through
+	ctx := context.WithoutCancel(c.Request().Context())
+	if err := ctrl.service.RestartServer(ctx, id, timeout); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]string{"status": "stopping"})
+	return c.JSON(http.StatusOK, map[string]string{"status": "starting"})
+}
+
+// parseTimeout reads a timeout in seconds, empty meaning the instance default
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid timeout")
+	}
+	return time.Duration(seconds) * time.Second, nil
 }
 
 // POST /api/servers/:id/command
@@ -144,7 +215,32 @@ func (ctrl *ServerController) Command(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "sent"})
 }
 
+type RCONRequest struct {
+	Command string `json:"command" form:"command"`
+}
+
+// POST /api/servers/:id/rcon
+func (ctrl *ServerController) RCON(c echo.Context) error {
+	id := c.Param("id")
+	var req RCONRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Command) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing command"})
+	}
+
+	response, err := ctrl.service.SendRCON(id, strings.TrimPrefix(strings.TrimSpace(req.Command), "/"))
+	if err != nil {
+		if errors.Is(err, minecraft.ErrRCONUnavailable) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"command": req.Command, "response": response})
+}
+
 // WS /api/servers/:id/ws
+// Server -> client: log, status, stats, crash, plus ack/error replies to commands.
+// Client -> server: {"type":"command","id":"1","data":"say hi"} and {"type":"ping","id":"2"}.
+// With ?format=structured, log data is a parsed line (level, thread, logger, message) instead of a string.
 func (ctrl *ServerController) Console(c echo.Context) error {
 	id := c.Param("id")
 
@@ -160,21 +256,229 @@ func (ctrl *ServerController) Console(c echo.Context) error {
 	}
 	defer ws.Close()
 
-	// Send history
-	history, _ := ctrl.service.GetServerLogHistory(id)
-	for _, line := range history {
-		msg := minecraft.WSMessage{Type: "log", Data: line}
-		if err := ws.WriteJSON(msg); err != nil {
-			break
+	// Read side: commands in, disconnect detection through the pong deadline
+	replies := make(chan minecraft.WSMessage, 16)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go ctrl.readConsole(ws, id, replies, done, stop)
+
+	// Send history: ?tail=N lines or everything after ?cursor= from the journal, else the in-memory buffer
+	structured := c.QueryParam("format") == "structured"
+	lastCursor, err := ctrl.sendConsoleHistory(ws, id, c.QueryParam("tail"), c.QueryParam("cursor"), structured)
+	if err != nil {
+		return nil
+	}
+
+	// Write side: everything goes through this loop, gorilla allows a single writer
+	pingTicker := time.NewTicker(wsPingPeriod)
+	defer pingTicker.Stop()
+	for {
+		select {
+		case msg, ok := <-stream:
+			if !ok {
+				return nil
+			}
+			// Lines subscribed to while the history was read are already sent
+			if msg.Cursor != "" && lastCursor != "" && !minecraft.CursorAfter(msg.Cursor, lastCursor) {
+				continue
+			}
+			if structured && msg.Log != nil {
+				msg.Data = msg.Log
+			}
+			if err := writeConsole(ws, msg); err != nil {
+				return nil
+			}
+		case msg := <-replies:
+			if err := writeConsole(ws, msg); err != nil {
+				return nil
+			}
+		case <-pingTicker.C:
+			ws.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return nil
+			}
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// Cap of journal lines replayed to a client resuming from a cursor
+const wsMaxResumeLines = 10000
+
+// sendConsoleHistory writes the initial console lines and returns the cursor of the last one
+func (ctrl *ServerController) sendConsoleHistory(ws *websocket.Conn, id, tailParam, cursor string, structured bool) (string, error) {
+	var parser minecraft.LogParser
+	logMessage := func(line, cursor string) minecraft.WSMessage {
+		msg := minecraft.WSMessage{Type: "log", Data: line, Cursor: cursor}
+		if structured {
+			parsed := parser.Parse(line)
+			msg.Data = &parsed
+		}
+		return msg
+	}
+
+	tail, _ := strconv.Atoi(tailParam)
+	if tail <= 0 && cursor == "" {
+		history, _ := ctrl.service.GetServerLogHistory(id)
+		for _, line := range history {
+			if err := writeConsole(ws, logMessage(line, "")); err != nil {
+				return "", err
+			}
 		}
+		return "", nil
 	}
 
-	for msg := range stream {
-		if err := ws.WriteJSON(msg); err != nil {
-			break
+	lastCursor := cursor
+	sent := 0
+	for {
+		page, err := ctrl.service.QueryConsole(id, minecraft.JournalQuery{Cursor: cursor, Limit: 1000}, min(tail, wsMaxResumeLines))
+		if err != nil {
+			writeConsole(ws, minecraft.WSMessage{Type: "error", Data: ConsoleReply{Error: err.Error()}})
+			return lastCursor, nil
+		}
+		for _, e := range page.Entries {
+			if err := writeConsole(ws, logMessage(e.Line, e.Cursor)); err != nil {
+				return "", err
+			}
+			lastCursor = e.Cursor
 		}
+		sent += len(page.Entries)
+		if page.Next == "" || sent >= wsMaxResumeLines {
+			return lastCursor, nil
+		}
+		cursor = page.Next
+	}
+}
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 4096
+)
+
+// ConsoleClientMessage is a frame sent by the console client
+type ConsoleClientMessage struct {
+	Type string `json:"type"` // command or ping
+	ID   string `json:"id"`   // Echoed back in the ack/error
+	Data string `json:"data"`
+}
+
+type ConsoleReply struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+func writeConsole(ws *websocket.Conn, msg minecraft.WSMessage) error {
+	ws.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return ws.WriteJSON(msg)
+}
+
+func (ctrl *ServerController) readConsole(ws *websocket.Conn, id string, replies chan<- minecraft.WSMessage, done chan struct{}, stop <-chan struct{}) {
+	defer close(done)
+
+	send := func(msg minecraft.WSMessage) bool {
+		select {
+		case replies <- msg:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	ws.SetReadLimit(wsMaxMessage)
+	ws.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg ConsoleClientMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				// Only this frame is bad, the connection is still usable
+				if !send(minecraft.WSMessage{Type: "error", Data: ConsoleReply{Error: "invalid message"}}) {
+					return
+				}
+				continue
+			}
+			return
+		}
+		ws.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var reply minecraft.WSMessage
+		switch msg.Type {
+		case "ping":
+			reply = minecraft.WSMessage{Type: "pong", Data: ConsoleReply{ID: msg.ID}}
+		case "command":
+			cmd := strings.TrimPrefix(strings.TrimSpace(msg.Data), "/")
+			err := fmt.Errorf("empty command")
+			if cmd != "" {
+				err = ctrl.service.SendCommand(id, cmd)
+			}
+			if err != nil {
+				reply = minecraft.WSMessage{Type: "error", Data: ConsoleReply{ID: msg.ID, Error: err.Error()}}
+			} else {
+				reply = minecraft.WSMessage{Type: "ack", Data: ConsoleReply{ID: msg.ID}}
+			}
+		default:
+			reply = minecraft.WSMessage{Type: "error", Data: ConsoleReply{ID: msg.ID, Error: fmt.Sprintf("unknown message type %q", msg.Type)}}
+		}
+		if !send(reply) {
+			return
+		}
+	}
+}
+
+// GET /api/servers/:id/console?since=&until=&q=&level=&cursor=&limit=&tail=
+func (ctrl *ServerController) ConsoleHistory(c echo.Context) error {
+	id := c.Param("id")
+
+	since, err := parseTime(c.QueryParam("since"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid since"})
+	}
+	until, err := parseTime(c.QueryParam("until"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid until"})
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit > 5000 {
+		limit = 5000
+	}
+	tail, _ := strconv.Atoi(c.QueryParam("tail"))
+	if tail > 5000 {
+		tail = 5000
 	}
-	return nil
+
+	query := minecraft.JournalQuery{
+		Cursor: c.QueryParam("cursor"),
+		Since:  since,
+		Until:  until,
+		Search: c.QueryParam("q"),
+		Level:  c.QueryParam("level"),
+		Limit:  limit,
+	}
+	page, err := ctrl.service.QueryConsole(id, query, tail)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// parseTime accepts RFC 3339 or unix seconds, empty meaning no bound
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
 // GET /api/servers/:id/properties
@@ -202,6 +506,150 @@ func (ctrl *ServerController) UpdateProperties(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
 }
 
+// GET /api/servers/:id/restart-policy
+func (ctrl *ServerController) GetRestartPolicy(c echo.Context) error {
+	id := c.Param("id")
+	cfg, err := ctrl.service.GetServer(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Server not found"})
+	}
+	return c.JSON(http.StatusOK, RestartPolicyRequest{
+		Policy:     cfg.RestartPolicy,
+		MaxRetries: cfg.RestartMaxRetries,
+		Backoff:    cfg.RestartBackoff,
+	})
+}
+
+type RestartPolicyRequest struct {
+	Policy     core.RestartPolicy `json:"policy"`
+	MaxRetries int                `json:"max_retries"`
+	Backoff    int                `json:"backoff"` // Seconds
+}
+
+// PUT /api/servers/:id/restart-policy
+func (ctrl *ServerController) UpdateRestartPolicy(c echo.Context) error {
+	id := c.Param("id")
+	var req RestartPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	}
+
+	if err := ctrl.service.UpdateRestartPolicy(id, req.Policy, req.MaxRetries, req.Backoff); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// GET /api/servers/:id/startup
+func (ctrl *ServerController) GetStartupSettings(c echo.Context) error {
+	id := c.Param("id")
+	cfg, err := ctrl.service.GetServer(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Server not found"})
+	}
+	return c.JSON(http.StatusOK, StartupSettingsRequest{
+		ReadyPattern:   cfg.ReadyPattern,
+		StartupTimeout: cfg.StartupTimeout,
+	})
+}
+
+type StartupSettingsRequest struct {
+	ReadyPattern   string `json:"ready_pattern"`
+	StartupTimeout int    `json:"startup_timeout"` // Seconds
+}
+
+// PUT /api/servers/:id/startup
+func (ctrl *ServerController) UpdateStartupSettings(c echo.Context) error {
+	id := c.Param("id")
+	var req StartupSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	}
+
+	if err := ctrl.service.UpdateStartupSettings(id, req.ReadyPattern, req.StartupTimeout); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// GET /api/servers/:id/stop-settings
+func (ctrl *ServerController) GetStopSettings(c echo.Context) error {
+	id := c.Param("id")
+	cfg, err := ctrl.service.GetServer(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Server not found"})
+	}
+	return c.JSON(http.StatusOK, StopSettingsRequest{
+		StopTimeout: cfg.StopTimeout,
+		TermGrace:   cfg.TermGrace,
+	})
+}
+
+type StopSettingsRequest struct {
+	StopTimeout int `json:"stop_timeout"` // Seconds, 0 = default
+	TermGrace   int `json:"term_grace"`   // Seconds, 0 = default
+}
+
+// PUT /api/servers/:id/stop-settings
+func (ctrl *ServerController) UpdateStopSettings(c echo.Context) error {
+	id := c.Param("id")
+	var req StopSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	}
+
+	if err := ctrl.service.UpdateStopSettings(id, req.StopTimeout, req.TermGrace); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// GET /api/servers/:id/launch
+func (ctrl *ServerController) GetLaunchArgs(c echo.Context) error {
+	id := c.Param("id")
+	cfg, err := ctrl.service.GetServer(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Server not found"})
+	}
+	return c.JSON(http.StatusOK, LaunchArgsRequest{
+		JVMArgs:    cfg.JVMArgs,
+		ServerArgs: cfg.ServerArgs,
+	})
+}
+
+type LaunchArgsRequest struct {
+	Preset     string   `json:"preset,omitempty"` // Replaces jvm_args when set
+	JVMArgs    []string `json:"jvm_args"`
+	ServerArgs []string `json:"server_args"`
+}
+
+// PUT /api/servers/:id/launch
+func (ctrl *ServerController) UpdateLaunchArgs(c echo.Context) error {
+	id := c.Param("id")
+	var req LaunchArgsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	}
+
+	cfg, err := ctrl.service.UpdateLaunchArgs(id, req.Preset, req.JVMArgs, req.ServerArgs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, LaunchArgsRequest{
+		JVMArgs:    cfg.JVMArgs,
+		ServerArgs: cfg.ServerArgs,
+	})
+}
+
+// GET /api/meta/jvm-presets
+func (ctrl *ServerController) GetJVMPresets(c echo.Context) error {
+	return c.JSON(http.StatusOK, minecraft.JVMPresets)
+}
+
 // GET /api/meta/versions
 func (ctrl *ServerController) GetVersions(c echo.Context) error {
 	versions, err := ctrl.service.GetVersions()
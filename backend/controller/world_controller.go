@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/ZiplEix/crafteur/core"
 	"github.com/ZiplEix/crafteur/services"
 	"github.com/labstack/echo/v4"
 )
@@ -32,7 +34,13 @@ func (c *WorldController) ListWorlds(ctx echo.Context) error {
 }
 
 type CreateWorldRequest struct {
-	Name string `json:"name"`
+	Name               string `json:"name"`
+	Seed               string `json:"seed"`
+	LevelType          string `json:"level_type"` // normal (default), flat, amplified, large_biomes
+	GeneratorSettings  string `json:"generator_settings"`
+	GenerateStructures *bool  `json:"generate_structures"` // Default true
+	Hardcore           bool   `json:"hardcore"`
+	Pregenerate        bool   `json:"pregenerate"` // Start the server once to generate spawn, it must be stopped
 }
 
 func (c *WorldController) CreateWorld(ctx echo.Context) error {
@@ -50,10 +58,20 @@ func (c *WorldController) CreateWorld(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "World name is required"})
 	}
 
-	if err := c.worldService.CreateWorld(serverID, req.Name); err != nil {
+	settings := core.WorldSettings{
+		Seed:               req.Seed,
+		LevelType:          req.LevelType,
+		GeneratorSettings:  req.GeneratorSettings,
+		GenerateStructures: req.GenerateStructures == nil || *req.GenerateStructures,
+		Hardcore:           req.Hardcore,
+	}
+	if err := c.worldService.CreateWorld(serverID, req.Name, settings, req.Pregenerate); err != nil {
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
+	if req.Pregenerate {
+		return ctx.JSON(http.StatusAccepted, map[string]string{"message": "World created, pre-generating"})
+	}
 	return ctx.JSON(http.StatusCreated, map[string]string{"message": "World created"})
 }
 
@@ -65,11 +83,16 @@ func (c *WorldController) ActivateWorld(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Server ID and World Name are required"})
 	}
 
-	if err := c.worldService.ActivateWorld(serverID, worldName); err != nil {
+	warning, err := c.worldService.ActivateWorld(serverID, worldName)
+	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return ctx.JSON(http.StatusOK, map[string]string{"message": "World activated"})
+	res := map[string]string{"message": "World activated"}
+	if warning != "" {
+		res["warning"] = warning
+	}
+	return ctx.JSON(http.StatusOK, res)
 }
 
 func (c *WorldController) DeleteWorld(ctx echo.Context) error {
@@ -86,3 +109,50 @@ func (c *WorldController) DeleteWorld(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, map[string]string{"message": "World deleted"})
 }
+
+// POST /api/servers/:id/worlds/import (multipart: "file" .zip/.tar.gz/.tgz, optional "name")
+func (c *WorldController) ImportWorld(ctx echo.Context) error {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Archive file is required"})
+	}
+
+	result, err := c.worldService.ImportWorld(ctx.Param("id"), ctx.FormValue("name"), file)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusCreated, result)
+}
+
+// GET /api/servers/:id/worlds/:name/export?format=zip|tar.gz (default: zip)
+func (c *WorldController) ExportWorld(ctx echo.Context) error {
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = "zip"
+	}
+	if format != "zip" && format != "tar.gz" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "format must be zip or tar.gz"})
+	}
+
+	archive, err := c.worldService.ExportWorld(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", archive.Name+"."+format))
+	if format == "zip" {
+		res.Header().Set(echo.HeaderContentType, "application/zip")
+		res.WriteHeader(http.StatusOK)
+		err = archive.WriteZip(res)
+	} else {
+		res.Header().Set(echo.HeaderContentType, "application/gzip")
+		res.WriteHeader(http.StatusOK)
+		err = archive.WriteTarGz(res)
+	}
+	if err != nil {
+		// Headers are gone, the client gets a truncated archive
+		fmt.Printf("Erreur export monde %s: %v\n", archive.Name, err)
+	}
+	return nil
+}
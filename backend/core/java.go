@@ -0,0 +1,43 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+type JavaRuntime struct {
+	ID      string `json:"id"`      // Directory name, e.g. "java-17-openjdk-amd64"
+	Home    string `json:"home"`    // JAVA_HOME
+	Path    string `json:"path"`    // bin/java
+	Version string `json:"version"` // Full version, e.g. "17.0.9"
+	Major   int    `json:"major"`
+	Managed bool   `json:"managed"` // Installed by crafteur in the runtimes directory
+}
+
+// RecommendedJavaVersion returns the Java major version Mojang ships for a Minecraft release.
+func RecommendedJavaVersion(mcVersion string) int {
+	parts := strings.Split(mcVersion, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		// Snapshots and the new year-based versions (26.1...) need the latest
+		return 21
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 21
+	}
+	patch := 0
+	if len(parts) > 2 {
+		patch, _ = strconv.Atoi(parts[2])
+	}
+
+	switch {
+	case minor < 17:
+		return 8
+	case minor == 17:
+		return 16
+	case minor < 20 || (minor == 20 && patch < 5):
+		return 17
+	default:
+		return 21
+	}
+}
@@ -0,0 +1,19 @@
+package core
+
+// MetricSample is one point of a server's resource history. Downsampled points hold the
+// average over their bucket, plus the peaks for CPU and RAM.
+type MetricSample struct {
+	Time    int64   `json:"time"` // Unix seconds, start of the bucket
+	Cpu     float64 `json:"cpu"`  // %
+	CpuPeak float64 `json:"cpu_peak"`
+	Ram     uint64  `json:"ram"` // Octets (RSS)
+	RamPeak uint64  `json:"ram_peak"`
+	RamMax  uint64  `json:"ram_max"` // Octets (Xmx)
+	Threads int     `json:"threads"`
+	Disk    uint64  `json:"disk"` // Octets used by the server directory
+	Players int     `json:"players"`
+	// Tick health, nil when it couldn't be measured
+	TPS      *float64 `json:"tps"`
+	MSPT     *float64 `json:"mspt"`
+	ServerID string   `json:"-"`
+}
@@ -0,0 +1,33 @@
+package core
+
+// PlayerSession is one stay of a player on a server, LeftAt is 0 while connected
+type PlayerSession struct {
+	ID       int64  `json:"id"`
+	ServerID string `json:"server_id"`
+	Name     string `json:"name"`
+	UUID     string `json:"uuid,omitempty"`
+	IP       string `json:"ip,omitempty"`
+	JoinedAt int64  `json:"joined_at"` // Unix seconds
+	LeftAt   int64  `json:"left_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type PlayerPlaytime struct {
+	Name     string `json:"name"`
+	UUID     string `json:"uuid,omitempty"`
+	Playtime int64  `json:"playtime"` // Seconds, the current session included
+	Sessions int    `json:"sessions"`
+	LastSeen int64  `json:"last_seen"` // Unix seconds, now when online
+	Online   bool   `json:"online"`
+}
+
+type DailyActivePlayers struct {
+	Date    string `json:"date"` // YYYY-MM-DD, server local time
+	Players int    `json:"players"`
+}
+
+type PlayerActivity struct {
+	PeakConcurrent int                  `json:"peak_concurrent"`
+	PeakAt         int64                `json:"peak_at,omitempty"` // Unix seconds
+	DailyActive    []DailyActivePlayers `json:"daily_active"`
+}
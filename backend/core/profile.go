@@ -0,0 +1,8 @@
+package core
+
+type PlayerProfile struct {
+	UUID      string `json:"uuid"` // With dashes
+	Name      string `json:"name"`
+	Offline   bool   `json:"offline,omitempty"`    // Computed for an offline-mode server, not a Mojang account
+	FetchedAt int64  `json:"fetched_at,omitempty"` // Unix seconds, when Mojang was last asked
+}
@@ -7,8 +7,25 @@ const (
 	StatusStarting ServerStatus = "STARTING"
 	StatusRunning  ServerStatus = "RUNNING"
 	StatusStopping ServerStatus = "STOPPING"
+	StatusCrashed  ServerStatus = "CRASHED"
 )
 
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+func (p RestartPolicy) IsValid() bool {
+	switch p {
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return true
+	}
+	return false
+}
+
 type ServerType string
 
 const (
@@ -27,6 +44,29 @@ type ServerConfig struct {
 	JavaVersion int        `json:"java_version"`
 	Version     string     `json:"version"` // Minecraft version (e.g. 1.20.4)
 	JarName     string     `json:"jar_name"`
+
+	RestartPolicy     RestartPolicy `json:"restart_policy"`
+	RestartMaxRetries int           `json:"restart_max_retries"`
+	RestartBackoff    int           `json:"restart_backoff"` // Seconds before the first retry, doubled on each attempt
+
+	ReadyPattern   string `json:"ready_pattern"`   // Regex marking the server as ready, empty = vanilla "Done" line
+	StartupTimeout int    `json:"startup_timeout"` // Seconds
+
+	StopTimeout int `json:"stop_timeout"` // Seconds to wait after "stop" before a SIGTERM
+	TermGrace   int `json:"term_grace"`   // Seconds to wait after the SIGTERM before a SIGKILL
+
+	JVMArgs    []string `json:"jvm_args"`    // Extra JVM flags, memory excluded
+	ServerArgs []string `json:"server_args"` // Arguments after the jar, e.g. --forceUpgrade
+}
+
+// ServerUpdateRequest is a partial update of a server's settings, nil fields are left untouched
+type ServerUpdateRequest struct {
+	Name        *string     `json:"name"`
+	Type        *ServerType `json:"type"`
+	Port        *int        `json:"port"`
+	RAM         *int        `json:"ram"`
+	JavaVersion *int        `json:"java_version"`
+	JarName     *string     `json:"jar_name"`
 }
 
 type User struct {
@@ -53,4 +93,8 @@ type ServerDetailResponse struct {
 	JavaVersion int          `json:"java_version"`
 	Version     string       `json:"version"`
 	Status      ServerStatus `json:"status"`
+
+	RestartPolicy RestartPolicy `json:"restart_policy"`
+	RestartCount  int           `json:"restart_count"`
+	BootTime      float64       `json:"boot_time,omitempty"` // Seconds taken by the last start to become ready
 }
@@ -0,0 +1,10 @@
+package core
+
+// WorldSettings are the generation options a world was created with from the panel
+type WorldSettings struct {
+	Seed               string `json:"seed"`
+	LevelType          string `json:"level_type"`         // normal, flat, amplified, large_biomes
+	GeneratorSettings  string `json:"generator_settings"` // JSON, e.g. the layers of a flat world
+	GenerateStructures bool   `json:"generate_structures"`
+	Hardcore           bool   `json:"hardcore"`
+}
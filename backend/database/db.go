@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 
@@ -30,7 +31,16 @@ func InitDB() {
 		ram INTEGER DEFAULT 2048,
 		java_version INTEGER DEFAULT 21,
 		version TEXT,
-		jar_name TEXT DEFAULT 'server.jar'
+		jar_name TEXT DEFAULT 'server.jar',
+		restart_policy TEXT DEFAULT 'never',
+		restart_max_retries INTEGER DEFAULT 3,
+		restart_backoff INTEGER DEFAULT 10,
+		ready_pattern TEXT DEFAULT '',
+		startup_timeout INTEGER DEFAULT 300,
+		jvm_args TEXT DEFAULT '[]',
+		server_args TEXT DEFAULT '[]',
+		stop_timeout INTEGER DEFAULT 30,
+		term_grace INTEGER DEFAULT 10
 	);
 	
 	CREATE TABLE IF NOT EXISTS users (
@@ -48,9 +58,109 @@ func InitDB() {
 		cron_expression TEXT,
 		one_shot BOOLEAN,
 		last_run DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS metrics (
+		server_id TEXT,
+		resolution INTEGER,
+		ts INTEGER,
+		cpu REAL,
+		cpu_peak REAL,
+		ram INTEGER,
+		ram_peak INTEGER,
+		ram_max INTEGER,
+		threads INTEGER,
+		disk INTEGER,
+		players INTEGER,
+		tps REAL,
+		mspt REAL,
+		PRIMARY KEY (server_id, resolution, ts)
+	);
+
+	CREATE TABLE IF NOT EXISTS player_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT,
+		name TEXT,
+		uuid TEXT DEFAULT '',
+		ip TEXT DEFAULT '',
+		joined_at INTEGER,
+		left_at INTEGER,
+		reason TEXT DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_player_sessions_server ON player_sessions (server_id, joined_at);
+
+	CREATE TABLE IF NOT EXISTS player_profiles (
+		uuid TEXT PRIMARY KEY,
+		name TEXT,
+		fetched_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_player_profiles_name ON player_profiles (name COLLATE NOCASE);
+
+	CREATE TABLE IF NOT EXISTS world_settings (
+		server_id TEXT,
+		world TEXT,
+		seed TEXT DEFAULT '',
+		level_type TEXT DEFAULT 'normal',
+		generator_settings TEXT DEFAULT '',
+		generate_structures BOOLEAN DEFAULT 1,
+		hardcore BOOLEAN DEFAULT 0,
+		PRIMARY KEY (server_id, world)
 	);`
 
 	if _, err := DB.Exec(query); err != nil {
 		log.Fatal("Erreur création table:", err)
 	}
+
+	// Columns added after the initial schema, for databases created by older versions
+	migrations := []struct{ table, column, definition string }{
+		{"servers", "restart_policy", "TEXT DEFAULT 'never'"},
+		{"servers", "restart_max_retries", "INTEGER DEFAULT 3"},
+		{"servers", "restart_backoff", "INTEGER DEFAULT 10"},
+		{"servers", "ready_pattern", "TEXT DEFAULT ''"},
+		{"servers", "startup_timeout", "INTEGER DEFAULT 300"},
+		{"servers", "jvm_args", "TEXT DEFAULT '[]'"},
+		{"servers", "server_args", "TEXT DEFAULT '[]'"},
+		{"servers", "stop_timeout", "INTEGER DEFAULT 30"},
+		{"servers", "term_grace", "INTEGER DEFAULT 10"},
+		{"metrics", "tps", "REAL"},
+		{"metrics", "mspt", "REAL"},
+	}
+	for _, m := range migrations {
+		if err := addColumnIfMissing(m.table, m.column, m.definition); err != nil {
+			log.Fatal("Erreur migration table:", err)
+		}
+	}
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there.
+func addColumnIfMissing(table, column, definition string) error {
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
 }
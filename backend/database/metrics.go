@@ -0,0 +1,63 @@
+package database
+
+import (
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// Metrics are stored per resolution, in seconds: 0 for raw samples, then the downsampled tiers.
+// Averages are averaged again when rolling up, peaks keep their maximum. AVG skips the
+// NULL tps/mspt of samples taken without a tick measurement.
+const metricAggregates = `AVG(cpu), MAX(cpu_peak), CAST(AVG(ram) AS INTEGER), MAX(ram_peak), MAX(ram_max),
+	CAST(ROUND(AVG(threads)) AS INTEGER), MAX(disk), MAX(players), AVG(tps), AVG(mspt)`
+
+func InsertMetric(m *core.MetricSample) error {
+	_, err := DB.Exec(`INSERT OR REPLACE INTO metrics (server_id, resolution, ts, cpu, cpu_peak, ram, ram_peak, ram_max, threads, disk, players, tps, mspt) VALUES (?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ServerID, m.Time, m.Cpu, m.CpuPeak, m.Ram, m.RamPeak, m.RamMax, m.Threads, m.Disk, m.Players, m.TPS, m.MSPT)
+	return err
+}
+
+// RollupMetrics aggregates the samples of resolution from into buckets of resolution to,
+// for every bucket starting in [since, before). Buckets already rolled up are recomputed.
+func RollupMetrics(from, to int, since, before int64) error {
+	_, err := DB.Exec(`INSERT OR REPLACE INTO metrics (server_id, resolution, ts, cpu, cpu_peak, ram, ram_peak, ram_max, threads, disk, players, tps, mspt)
+		SELECT server_id, ?, (ts / ?) * ?, `+metricAggregates+`
+		FROM metrics WHERE resolution = ? AND ts >= ? AND ts < ?
+		GROUP BY server_id, ts / ?`,
+		to, to, to, from, since, before, to)
+	return err
+}
+
+func PruneMetrics(resolution int, before int64) error {
+	_, err := DB.Exec(`DELETE FROM metrics WHERE resolution = ? AND ts < ?`, resolution, before)
+	return err
+}
+
+// QueryMetrics returns the samples of a resolution in [from, to], grouped into buckets of step seconds
+func QueryMetrics(serverID string, resolution int, step, from, to int64) ([]core.MetricSample, error) {
+	if step <= 0 {
+		step = 1
+	}
+	rows, err := DB.Query(`SELECT (ts / ?) * ? AS bucket, `+metricAggregates+`
+		FROM metrics WHERE server_id = ? AND resolution = ? AND ts >= ? AND ts <= ?
+		GROUP BY bucket ORDER BY bucket`,
+		step, step, serverID, resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []core.MetricSample{}
+	for rows.Next() {
+		m := core.MetricSample{ServerID: serverID}
+		if err := rows.Scan(&m.Time, &m.Cpu, &m.CpuPeak, &m.Ram, &m.RamPeak, &m.RamMax, &m.Threads, &m.Disk, &m.Players, &m.TPS, &m.MSPT); err != nil {
+			return nil, err
+		}
+		samples = append(samples, m)
+	}
+	return samples, rows.Err()
+}
+
+func DeleteMetricsByServer(serverID string) error {
+	_, err := DB.Exec(`DELETE FROM metrics WHERE server_id = ?`, serverID)
+	return err
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+const sessionColumns = `id, server_id, name, uuid, ip, joined_at, left_at, reason`
+
+// OpenPlayerSession records a join, closing a session the player never left (missed leave line)
+func OpenPlayerSession(s *core.PlayerSession) error {
+	if err := ClosePlayerSession(s.ServerID, s.Name, "", s.JoinedAt); err != nil {
+		return err
+	}
+	res, err := DB.Exec(`INSERT INTO player_sessions (server_id, name, uuid, ip, joined_at, reason) VALUES (?, ?, ?, ?, ?, '')`,
+		s.ServerID, s.Name, s.UUID, s.IP, s.JoinedAt)
+	if err != nil {
+		return err
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+func ClosePlayerSession(serverID, name, reason string, leftAt int64) error {
+	_, err := DB.Exec(`UPDATE player_sessions SET left_at = ?, reason = ? WHERE server_id = ? AND name = ? AND left_at IS NULL`,
+		leftAt, reason, serverID, name)
+	return err
+}
+
+// SyncPlayerSessions makes the open sessions match the players actually online: sessions of
+// players gone are closed, online players without one get a session starting now.
+func SyncPlayerSessions(serverID string, online []string, now int64) error {
+	open, err := GetOpenPlayerSessions(serverID)
+	if err != nil {
+		return err
+	}
+	isOnline := make(map[string]bool, len(online))
+	for _, name := range online {
+		isOnline[name] = true
+	}
+	hasSession := make(map[string]bool, len(open))
+	for _, s := range open {
+		hasSession[s.Name] = true
+		if !isOnline[s.Name] {
+			if err := ClosePlayerSession(serverID, s.Name, "", now); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range online {
+		if !hasSession[name] {
+			if err := OpenPlayerSession(&core.PlayerSession{ServerID: serverID, Name: name, JoinedAt: now}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func GetOpenPlayerSessions(serverID string) ([]core.PlayerSession, error) {
+	return queryPlayerSessions(`SELECT `+sessionColumns+` FROM player_sessions WHERE server_id = ? AND left_at IS NULL`, serverID)
+}
+
+// GetPlayerSessions returns the sessions overlapping [from, to], newest first. An empty player means all.
+func GetPlayerSessions(serverID, player string, from, to int64, limit int) ([]core.PlayerSession, error) {
+	return queryPlayerSessions(`SELECT `+sessionColumns+` FROM player_sessions
+		WHERE server_id = ? AND (? = '' OR name = ?) AND joined_at <= ? AND (left_at IS NULL OR left_at >= ?)
+		ORDER BY joined_at DESC LIMIT ?`,
+		serverID, player, player, to, from, limit)
+}
+
+// GetPlayerPlaytimes sums the sessions of every player, open sessions counting until now
+func GetPlayerPlaytimes(serverID string, now int64) ([]core.PlayerPlaytime, error) {
+	rows, err := DB.Query(`SELECT name, MAX(uuid), SUM(COALESCE(left_at, ?) - joined_at), COUNT(*),
+			MAX(COALESCE(left_at, ?)), MAX(left_at IS NULL)
+		FROM player_sessions WHERE server_id = ?
+		GROUP BY name ORDER BY 3 DESC`,
+		now, now, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	playtimes := []core.PlayerPlaytime{}
+	for rows.Next() {
+		var p core.PlayerPlaytime
+		if err := rows.Scan(&p.Name, &p.UUID, &p.Playtime, &p.Sessions, &p.LastSeen, &p.Online); err != nil {
+			return nil, err
+		}
+		playtimes = append(playtimes, p)
+	}
+	return playtimes, rows.Err()
+}
+
+// FindPlayerUUID returns the last UUID seen for a name on any server, empty when unknown
+func FindPlayerUUID(name string) (string, error) {
+	var uuid string
+	err := DB.QueryRow(`SELECT uuid FROM player_sessions WHERE name = ? COLLATE NOCASE AND uuid != '' ORDER BY joined_at DESC LIMIT 1`, name).Scan(&uuid)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return uuid, err
+}
+
+func DeletePlayerSessionsByServer(serverID string) error {
+	_, err := DB.Exec(`DELETE FROM player_sessions WHERE server_id = ?`, serverID)
+	return err
+}
+
+func queryPlayerSessions(query string, args ...any) ([]core.PlayerSession, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []core.PlayerSession{}
+	for rows.Next() {
+		var s core.PlayerSession
+		var leftAt sql.NullInt64
+		if err := rows.Scan(&s.ID, &s.ServerID, &s.Name, &s.UUID, &s.IP, &s.JoinedAt, &leftAt, &s.Reason); err != nil {
+			return nil, err
+		}
+		s.LeftAt = leftAt.Int64
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
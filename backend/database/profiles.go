@@ -0,0 +1,44 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// Profiles resolved through Mojang's API, shared by every server
+func GetProfileByName(name string) (*core.PlayerProfile, error) {
+	return scanProfile(DB.QueryRow(`SELECT uuid, name, fetched_at FROM player_profiles WHERE name = ? COLLATE NOCASE ORDER BY fetched_at DESC LIMIT 1`, name))
+}
+
+func GetProfileByUUID(uuid string) (*core.PlayerProfile, error) {
+	return scanProfile(DB.QueryRow(`SELECT uuid, name, fetched_at FROM player_profiles WHERE uuid = ?`, uuid))
+}
+
+// SaveProfile stores a profile. Another account that used the same name loses it.
+func SaveProfile(p *core.PlayerProfile) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM player_profiles WHERE name = ? COLLATE NOCASE AND uuid != ?`, p.Name, p.UUID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO player_profiles (uuid, name, fetched_at) VALUES (?, ?, ?)`, p.UUID, p.Name, p.FetchedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanProfile(row *sql.Row) (*core.PlayerProfile, error) {
+	var p core.PlayerProfile
+	if err := row.Scan(&p.UUID, &p.Name, &p.FetchedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
@@ -2,12 +2,83 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/ZiplEix/crafteur/core"
 )
 
+const serverColumns = "id, name, type, port, ram, java_version, version, jar_name, restart_policy, restart_max_retries, restart_backoff, ready_pattern, startup_timeout, jvm_args, server_args, stop_timeout, term_grace"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanServer(row rowScanner) (*core.ServerConfig, error) {
+	var s core.ServerConfig
+	var jarName sql.NullString // Handle potential nulls safely for old rows if migration missed (though default takes care)
+	var restartPolicy sql.NullString
+	var restartMaxRetries, restartBackoff sql.NullInt64
+	var readyPattern sql.NullString
+	var startupTimeout sql.NullInt64
+	var jvmArgs, serverArgs sql.NullString
+	var stopTimeout, termGrace sql.NullInt64
+	if err := row.Scan(&s.ID, &s.Name, &s.Type, &s.Port, &s.RAM, &s.JavaVersion, &s.Version, &jarName, &restartPolicy, &restartMaxRetries, &restartBackoff, &readyPattern, &startupTimeout, &jvmArgs, &serverArgs, &stopTimeout, &termGrace); err != nil {
+		return nil, err
+	}
+	if jarName.Valid {
+		s.JarName = jarName.String
+	} else {
+		s.JarName = "server.jar"
+	}
+	s.RestartPolicy = core.RestartNever
+	if restartPolicy.Valid && core.RestartPolicy(restartPolicy.String).IsValid() {
+		s.RestartPolicy = core.RestartPolicy(restartPolicy.String)
+	}
+	s.RestartMaxRetries = 3
+	if restartMaxRetries.Valid {
+		s.RestartMaxRetries = int(restartMaxRetries.Int64)
+	}
+	s.RestartBackoff = 10
+	if restartBackoff.Valid {
+		s.RestartBackoff = int(restartBackoff.Int64)
+	}
+	s.ReadyPattern = readyPattern.String
+	s.StartupTimeout = 300
+	if startupTimeout.Valid {
+		s.StartupTimeout = int(startupTimeout.Int64)
+	}
+	s.JVMArgs = decodeArgs(jvmArgs)
+	s.ServerArgs = decodeArgs(serverArgs)
+	s.StopTimeout = 30
+	if stopTimeout.Valid {
+		s.StopTimeout = int(stopTimeout.Int64)
+	}
+	s.TermGrace = 10
+	if termGrace.Valid {
+		s.TermGrace = int(termGrace.Int64)
+	}
+	return &s, nil
+}
+
+// Argument lists are stored as JSON arrays so arguments may contain spaces
+func decodeArgs(value sql.NullString) []string {
+	args := []string{}
+	if value.Valid && value.String != "" {
+		json.Unmarshal([]byte(value.String), &args)
+	}
+	return args
+}
+
+func encodeArgs(args []string) string {
+	if args == nil {
+		args = []string{}
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
 func GetAllServers() ([]core.ServerConfig, error) {
-	rows, err := DB.Query("SELECT id, name, type, port, ram, java_version, version, jar_name FROM servers")
+	rows, err := DB.Query("SELECT " + serverColumns + " FROM servers")
 	if err != nil {
 		return nil, err
 	}
@@ -15,44 +86,72 @@ func GetAllServers() ([]core.ServerConfig, error) {
 
 	var servers []core.ServerConfig
 	for rows.Next() {
-		var s core.ServerConfig
-		var jarName sql.NullString // Handle potential nulls safely for old rows if migration missed (though default takes care)
-		if err := rows.Scan(&s.ID, &s.Name, &s.Type, &s.Port, &s.RAM, &s.JavaVersion, &s.Version, &jarName); err != nil {
+		s, err := scanServer(rows)
+		if err != nil {
 			return nil, err
 		}
-		if jarName.Valid {
-			s.JarName = jarName.String
-		} else {
-			s.JarName = "server.jar"
-		}
-		servers = append(servers, s)
+		servers = append(servers, *s)
 	}
 	return servers, nil
 }
 
 func GetServer(id string) (*core.ServerConfig, error) {
-	var s core.ServerConfig
-	var jarName sql.NullString
-	err := DB.QueryRow("SELECT id, name, type, port, ram, java_version, version, jar_name FROM servers WHERE id = ?", id).Scan(&s.ID, &s.Name, &s.Type, &s.Port, &s.RAM, &s.JavaVersion, &s.Version, &jarName)
-	if err != nil {
-		return nil, err
-	}
-	if jarName.Valid {
-		s.JarName = jarName.String
-	} else {
-		s.JarName = "server.jar"
-	}
-	return &s, nil
+	return scanServer(DB.QueryRow("SELECT "+serverColumns+" FROM servers WHERE id = ?", id))
 }
 
 func CreateServer(s *core.ServerConfig) error {
 	_, err := DB.Exec(
-		"INSERT INTO servers (id, name, type, port, ram, java_version, version, jar_name) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-		s.ID, s.Name, s.Type, s.Port, s.RAM, s.JavaVersion, s.Version, s.JarName,
+		"INSERT INTO servers ("+serverColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		s.ID, s.Name, s.Type, s.Port, s.RAM, s.JavaVersion, s.Version, s.JarName, s.RestartPolicy, s.RestartMaxRetries, s.RestartBackoff, s.ReadyPattern, s.StartupTimeout, encodeArgs(s.JVMArgs), encodeArgs(s.ServerArgs), s.StopTimeout, s.TermGrace,
+	)
+	return err
+}
+
+func UpdateServer(s *core.ServerConfig) error {
+	_, err := DB.Exec(
+		"UPDATE servers SET name = ?, type = ?, port = ?, ram = ?, java_version = ?, version = ?, jar_name = ? WHERE id = ?",
+		s.Name, s.Type, s.Port, s.RAM, s.JavaVersion, s.Version, s.JarName, s.ID,
+	)
+	return err
+}
+
+func UpdateRestartPolicy(id string, policy core.RestartPolicy, maxRetries int, backoff int) error {
+	_, err := DB.Exec(
+		"UPDATE servers SET restart_policy = ?, restart_max_retries = ?, restart_backoff = ? WHERE id = ?",
+		policy, maxRetries, backoff, id,
+	)
+	return err
+}
+
+func UpdateStartupSettings(id string, readyPattern string, startupTimeout int) error {
+	_, err := DB.Exec(
+		"UPDATE servers SET ready_pattern = ?, startup_timeout = ? WHERE id = ?",
+		readyPattern, startupTimeout, id,
+	)
+	return err
+}
+
+func UpdateStopSettings(id string, stopTimeout, termGrace int) error {
+	_, err := DB.Exec(
+		"UPDATE servers SET stop_timeout = ?, term_grace = ? WHERE id = ?",
+		stopTimeout, termGrace, id,
 	)
 	return err
 }
 
+func UpdateLaunchArgs(id string, jvmArgs, serverArgs []string) error {
+	_, err := DB.Exec(
+		"UPDATE servers SET jvm_args = ?, server_args = ? WHERE id = ?",
+		encodeArgs(jvmArgs), encodeArgs(serverArgs), id,
+	)
+	return err
+}
+
+func UpdateJavaVersion(id string, javaVersion int) error {
+	_, err := DB.Exec("UPDATE servers SET java_version = ? WHERE id = ?", javaVersion, id)
+	return err
+}
+
 func DeleteServer(id string) error {
 	_, err := DB.Exec("DELETE FROM servers WHERE id = ?", id)
 	return err
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// Generation settings of the worlds created from the panel, applied when a world is activated
+func SaveWorldSettings(serverID, world string, w *core.WorldSettings) error {
+	_, err := DB.Exec(`INSERT OR REPLACE INTO world_settings (server_id, world, seed, level_type, generator_settings, generate_structures, hardcore) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		serverID, world, w.Seed, w.LevelType, w.GeneratorSettings, w.GenerateStructures, w.Hardcore)
+	return err
+}
+
+// GetWorldSettings returns nil when the world wasn't created from the panel
+func GetWorldSettings(serverID, world string) (*core.WorldSettings, error) {
+	var w core.WorldSettings
+	err := DB.QueryRow(`SELECT seed, level_type, generator_settings, generate_structures, hardcore FROM world_settings WHERE server_id = ? AND world = ?`, serverID, world).
+		Scan(&w.Seed, &w.LevelType, &w.GeneratorSettings, &w.GenerateStructures, &w.Hardcore)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+func DeleteWorldSettings(serverID, world string) error {
+	_, err := DB.Exec(`DELETE FROM world_settings WHERE server_id = ? AND world = ?`, serverID, world)
+	return err
+}
+
+func DeleteWorldSettingsByServer(serverID string) error {
+	_, err := DB.Exec(`DELETE FROM world_settings WHERE server_id = ?`, serverID)
+	return err
+}
@@ -32,6 +32,15 @@ func getFileSystem() http.FileSystem {
 }
 
 func main() {
+	// Supervisor mode: runs one Minecraft server detached from the panel (see minecraft.RunSupervisor)
+	if len(os.Args) > 1 && os.Args[1] == "supervise" {
+		if err := minecraft.RunSupervisor(os.Args[2:]); err != nil {
+			fmt.Printf("Supervisor error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	godotenv.Load()
 
 	database.InitDB()
@@ -66,14 +75,27 @@ func main() {
 	// Paper Service
 	paperService := services.NewPaperService()
 
-	// Server Service now needs FileService, FabricService and PaperService
-	serverService := services.NewServerService(mcManager, versionService, fileService, fabricService, paperService)
+	// Java runtimes: system JDKs plus the ones installed through the panel
+	runtimesDir := os.Getenv("JAVA_RUNTIMES_DIR")
+	if runtimesDir == "" {
+		runtimesDir = "data/runtimes"
+	}
+	javaService := services.NewJavaService(runtimesDir)
+
+	portService := services.NewPortService(mcManager, "data/servers")
+
+	// Server Service now needs FileService, FabricService, PaperService, JavaService and PortService
+	serverService := services.NewServerService(mcManager, versionService, fileService, fabricService, paperService, javaService, portService)
 
 	if err := serverService.LoadServersAtStartup(); err != nil {
 		log.Fatal("Can't load servers at startup:", err)
 	}
-	playerService := services.NewPlayerService(mcManager, "data")
+	// Name <-> UUID resolution, MOJANG_API_URL can point at a mirror or a local stub
+	profileService := services.NewProfileService(os.Getenv("MOJANG_API_URL"), services.DefaultProfileTTL, "data")
+	playerService := services.NewPlayerService(mcManager, "data", profileService)
 	logService := services.NewLogService("data/servers")
+	crashService := services.NewCrashService("data/servers")
+	metricsService := services.NewMetricsService(mcManager)
 	backupService := services.NewBackupService("data/servers", "data/backups")
 	schedulerService := services.NewSchedulerService(serverService)
 	worldService := services.NewWorldService(serverService, "data/servers")
@@ -84,11 +106,16 @@ func main() {
 	fileCtrl := controller.NewFileController(fileService)
 	playerCtrl := controller.NewPlayerController(playerService, serverService)
 	logCtrl := controller.NewLogController(logService)
+	crashCtrl := controller.NewCrashController(crashService)
 	backupCtrl := controller.NewBackupController(backupService)
 	schedulerCtrl := controller.NewSchedulerController(schedulerService)
 	worldCtrl := controller.NewWorldController(worldService)
 	addonCtrl := controller.NewAddonController(addonService)
 	modrinthCtrl := controller.NewModrinthController(modrinthService, serverService)
+	javaCtrl := controller.NewJavaController(javaService, serverService)
+	portCtrl := controller.NewPortController(portService)
+	exporterService := services.NewExporterService(serverService, backupService, schedulerService)
+	metricsCtrl := controller.NewMetricsController(metricsService, exporterService, os.Getenv("METRICS_TOKEN"))
 
 	e := echo.New()
 
@@ -99,6 +126,9 @@ func main() {
 	schedulerService.Start()
 	defer schedulerService.Stop()
 
+	metricsService.Start()
+	defer metricsService.Stop()
+
 	e.Use(middleware.RequestLogger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.RemoveTrailingSlash())
@@ -106,16 +136,17 @@ func main() {
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowCredentials: true,
 		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
+		ExposeHeaders:    []string{"X-Log-Truncated"},
 		AllowMethods:     []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
 	}))
 
-	routes.Register(e, serverCtrl, fileCtrl, playerCtrl, logCtrl, backupCtrl, schedulerCtrl, worldCtrl, addonCtrl, modrinthCtrl)
+	routes.Register(e, serverCtrl, fileCtrl, playerCtrl, logCtrl, backupCtrl, schedulerCtrl, worldCtrl, addonCtrl, modrinthCtrl, javaCtrl, portCtrl, crashCtrl, metricsCtrl)
 
 	e.Use(middleware.StaticWithConfig(middleware.StaticConfig{
 		Filesystem: getFileSystem(),
 		HTML5:      true,
 		Skipper: func(c echo.Context) bool {
-			return strings.HasPrefix(c.Path(), "/api")
+			return strings.HasPrefix(c.Path(), "/api") || c.Path() == "/metrics"
 		},
 	}))
 
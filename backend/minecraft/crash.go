@@ -0,0 +1,380 @@
+package minecraft
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CrashReport is a parsed crash-reports/crash-*.txt or JVM hs_err_pid*.log file
+type CrashReport struct {
+	File          string         `json:"file"` // Relative to the server directory
+	Kind          string         `json:"kind"` // "minecraft" or "jvm"
+	Time          time.Time      `json:"time"`
+	Description   string         `json:"description"`
+	Exception     string         `json:"exception,omitempty"`
+	Stack         []string       `json:"stack,omitempty"` // First frames of the exception
+	SuspectedMods []string       `json:"suspected_mods,omitempty"`
+	Findings      []CrashFinding `json:"findings"`
+}
+
+// CrashFinding is a known crash cause recognized in a report or in the console output
+type CrashFinding struct {
+	Rule       string `json:"rule"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail,omitempty"`
+	Suggestion string `json:"suggestion"`
+}
+
+const crashReportsDir = "crash-reports"
+
+var (
+	crashTimeRegex     = regexp.MustCompile(`^Time: (.+)$`)
+	crashFileTimeRegex = regexp.MustCompile(`crash-(\d{4}-\d{2}-\d{2}_\d{2}\.\d{2}\.\d{2})`)
+	hsErrVersionRegex  = regexp.MustCompile(`^# JRE version: (.+)$`)
+)
+
+// FindCrashFiles lists crash reports and JVM fatal error logs of a server directory, newest first
+func FindCrashFiles(runDir string) ([]string, error) {
+	files := []string{}
+
+	entries, err := os.ReadDir(filepath.Join(runDir, crashReportsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".txt") {
+			files = append(files, filepath.Join(crashReportsDir, e.Name()))
+		}
+	}
+
+	// The JVM writes hs_err_pid<pid>.log in its working directory
+	hsErr, _ := filepath.Glob(filepath.Join(runDir, "hs_err_pid*.log"))
+	for _, path := range hsErr {
+		files = append(files, filepath.Base(path))
+	}
+
+	modTimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if info, err := os.Stat(filepath.Join(runDir, f)); err == nil {
+			modTimes[f] = info.ModTime()
+		}
+	}
+	sort.Slice(files, func(a, b int) bool {
+		return modTimes[files[a]].After(modTimes[files[b]])
+	})
+	return files, nil
+}
+
+// ParseCrashFile parses one file returned by FindCrashFiles
+func ParseCrashFile(runDir, name string) (*CrashReport, error) {
+	path := filepath.Join(runDir, name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *CrashReport
+	if strings.HasPrefix(filepath.Base(name), "hs_err_pid") {
+		report = parseHsErr(string(content))
+	} else {
+		report = parseCrashReport(string(content))
+	}
+	report.File = name
+	if report.Time.IsZero() {
+		if m := crashFileTimeRegex.FindStringSubmatch(name); m != nil {
+			report.Time, _ = time.ParseInLocation("2006-01-02_15.04.05", m[1], time.Local)
+		}
+	}
+	if report.Time.IsZero() {
+		report.Time = info.ModTime()
+	}
+	report.Findings = AnalyzeCrash(string(content))
+	return report, nil
+}
+
+// parseCrashReport reads the "---- Minecraft Crash Report ----" format
+func parseCrashReport(content string) *CrashReport {
+	report := &CrashReport{Kind: "minecraft"}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	const (
+		header = iota
+		exception
+		stack
+		body
+		mods
+	)
+	state := header
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if state == mods {
+			if strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "\t\t") {
+				report.SuspectedMods = append(report.SuspectedMods, strings.TrimSpace(line))
+				continue
+			}
+			if strings.HasPrefix(line, "\t\t") {
+				continue // Issue tracker URLs and such
+			}
+			state = body
+		}
+
+		switch {
+		case state == header:
+			if m := crashTimeRegex.FindStringSubmatch(line); m != nil {
+				report.Time = parseCrashTime(m[1])
+			} else if desc, ok := strings.CutPrefix(line, "Description: "); ok {
+				report.Description = desc
+				state = exception
+			}
+		case state == exception:
+			if strings.TrimSpace(line) != "" {
+				report.Exception = line
+				state = stack
+			}
+		case state == stack:
+			if strings.TrimSpace(line) == "" {
+				state = body
+			} else if len(report.Stack) < 15 {
+				report.Stack = append(report.Stack, strings.TrimSpace(line))
+			}
+		case strings.HasPrefix(line, "Suspected Mod"):
+			// "Suspected Mods: NONE" or "Suspected Mods:" followed by one tab-indented mod per line
+			if value := strings.TrimSpace(line[strings.Index(line, ":")+1:]); value != "" && value != "NONE" && value != "Unknown" {
+				report.SuspectedMods = append(report.SuspectedMods, value)
+			}
+			state = mods
+		}
+	}
+	return report
+}
+
+func parseCrashTime(value string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04:05.000", "2006/01/02 15:04", "1/2/06 3:04 PM"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseHsErr reads the header of a JVM fatal error log:
+//
+//	# A fatal error has been detected by the Java Runtime Environment:
+//	#
+//	#  SIGSEGV (0xb) at pc=0x00007f..., pid=1234, tid=1235
+//	#
+//	# JRE version: OpenJDK Runtime Environment (17.0.9+9) (build 17.0.9+9)
+//	# Problematic frame:
+//	# C  [libc.so.6+0x1a2b3]
+func parseHsErr(content string) *CrashReport {
+	report := &CrashReport{Kind: "jvm"}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	frameNext := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !strings.HasPrefix(line, "#") {
+			break // End of the summary header
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		switch {
+		case text == "":
+		case frameNext:
+			report.Stack = append(report.Stack, text)
+			frameNext = false
+		case report.Description == "":
+			report.Description = strings.TrimSuffix(text, ":")
+		case report.Exception == "" && (strings.HasPrefix(text, "SIG") || strings.HasPrefix(text, "EXCEPTION_") || strings.HasPrefix(text, "Native memory allocation")):
+			report.Exception = text
+		case strings.HasPrefix(text, "Problematic frame"):
+			frameNext = true
+		default:
+			if m := hsErrVersionRegex.FindStringSubmatch(line); m != nil {
+				report.Stack = append(report.Stack, "JRE: "+m[1])
+			}
+		}
+	}
+	return report
+}
+
+type crashRule struct {
+	id      string
+	title   string
+	pattern *regexp.Regexp
+	// detail builds the specific part from the regex submatches
+	detail     func(m []string) string
+	suggestion string
+}
+
+// Known crash causes, checked against crash reports, JVM logs and the last console lines
+var crashRules = []crashRule{
+	{
+		id:      "wrong_java_version",
+		title:   "Wrong Java version",
+		pattern: regexp.MustCompile(`UnsupportedClassVersionError.*?class file version (\d+)(?:\.\d+)?(?:.*?up to (\d+))?`),
+		detail: func(m []string) string {
+			needed, _ := strconv.Atoi(m[1])
+			detail := "the server needs Java " + strconv.Itoa(needed-44)
+			if current, err := strconv.Atoi(m[2]); err == nil {
+				detail += ", it was started with Java " + strconv.Itoa(current-44)
+			}
+			return detail
+		},
+		suggestion: "Select a newer Java runtime in the server settings",
+	},
+	{
+		// Paper: "This version of Minecraft requires at least Java 21"
+		id:         "wrong_java_version",
+		title:      "Wrong Java version",
+		pattern:    regexp.MustCompile(`(?i)requires (?:at least )?Java (\d+)`),
+		detail:     func(m []string) string { return "the server needs Java " + m[1] },
+		suggestion: "Select the matching Java runtime in the server settings",
+	},
+	{
+		// Fabric: "Mod 'Sodium' (sodium) 0.5.8 requires any version of 'Fabric API' (fabric-api), which is missing!"
+		id:         "missing_dependency",
+		title:      "Mod missing a dependency",
+		pattern:    regexp.MustCompile(`Mod '([^']+)'[^\n]*? requires [^\n]*?of '?([^'(,\n]+?)'?(?: \([^)]*\))?, which is missing`),
+		detail:     func(m []string) string { return m[1] + " requires " + m[2] },
+		suggestion: "Install the missing mod (matching the loader and Minecraft version) or remove the mod that needs it",
+	},
+	{
+		// Forge: "Mod ID: 'jei', Requested by: 'somemod', Expected range: '[15,)', Actual version: '[MISSING]'"
+		id:         "missing_dependency",
+		title:      "Mod missing a dependency",
+		pattern:    regexp.MustCompile(`Mod ID: '([^']+)', Requested by: '([^']+)'`),
+		detail:     func(m []string) string { return m[2] + " requires " + m[1] },
+		suggestion: "Install the missing mod (matching the loader and Minecraft version) or remove the mod that needs it",
+	},
+	{
+		id:         "missing_dependency",
+		title:      "Incompatible mod set",
+		pattern:    regexp.MustCompile(`Incompatible mods? found!|Mod resolution encountered an incompatible mod set`),
+		detail:     func(m []string) string { return "the mod loader refused the installed mod set" },
+		suggestion: "Read the mod resolution message in the console and install or update the listed mods",
+	},
+	{
+		id:         "out_of_memory",
+		title:      "Out of memory",
+		pattern:    regexp.MustCompile(`java\.lang\.OutOfMemoryError(?:: ([^\n]+))?`),
+		detail:     func(m []string) string { return strings.TrimSpace(m[1]) },
+		suggestion: "Increase the server RAM, or look for a mod/plugin leaking memory if it keeps growing",
+	},
+	{
+		id:         "out_of_memory",
+		title:      "Out of native memory",
+		pattern:    regexp.MustCompile(`insufficient memory for the Java Runtime Environment|Native memory allocation \((?:mmap|malloc)\) failed`),
+		detail:     func(m []string) string { return "the host could not give the JVM the memory it asked for" },
+		suggestion: "Lower the server RAM or free memory on the host, the heap does not fit in the available memory",
+	},
+	{
+		id:      "mixin_failure",
+		title:   "Mixin failure",
+		pattern: regexp.MustCompile(`Mixin apply for mod (\S+) failed|MixinApplyError|MixinTransformerError|InvalidMixinException`),
+		detail: func(m []string) string {
+			if m[1] != "" {
+				return "mixin from mod " + m[1] + " could not be applied"
+			}
+			return ""
+		},
+		suggestion: "A mod is incompatible with another mod or with this Minecraft/loader version, update or remove it",
+	},
+	{
+		id:         "port_in_use",
+		title:      "Port already in use",
+		pattern:    regexp.MustCompile(`FAILED TO BIND TO PORT|Address already in use`),
+		detail:     func(m []string) string { return "" },
+		suggestion: "Another process or server uses the same port, change server-port",
+	},
+	{
+		id:         "watchdog",
+		title:      "Server hung",
+		pattern:    regexp.MustCompile(`A single server tick took ([\d.]+) seconds`),
+		detail:     func(m []string) string { return "one tick took " + m[1] + "s, the watchdog killed the server" },
+		suggestion: "Find what blocks the main thread (the stack shows it), or raise max-tick-time",
+	},
+}
+
+// AnalyzeCrash matches a crash report, a JVM log or console output against the known causes
+func AnalyzeCrash(text string) []CrashFinding {
+	findings := []CrashFinding{}
+	seen := make(map[string]bool)
+	for _, rule := range crashRules {
+		if seen[rule.id] {
+			continue
+		}
+		m := rule.pattern.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		seen[rule.id] = true
+		findings = append(findings, CrashFinding{
+			Rule:       rule.id,
+			Title:      rule.title,
+			Detail:     rule.detail(m),
+			Suggestion: rule.suggestion,
+		})
+	}
+	return findings
+}
+
+// CrashAnalysis is attached to the crash event: reports written during the run plus what the console showed
+type CrashAnalysis struct {
+	Reports  []CrashReport  `json:"reports"`
+	Findings []CrashFinding `json:"findings"`
+}
+
+// analyzeCrash looks at the crash files written since the process started and at the last console lines
+func (i *Instance) analyzeCrash(startedAt time.Time) *CrashAnalysis {
+	analysis := &CrashAnalysis{Reports: []CrashReport{}, Findings: []CrashFinding{}}
+
+	files, _ := FindCrashFiles(i.RunDir)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(i.RunDir, f))
+		if err != nil || info.ModTime().Before(startedAt) {
+			continue
+		}
+		report, err := ParseCrashFile(i.RunDir, f)
+		if err != nil {
+			continue
+		}
+		analysis.Reports = append(analysis.Reports, *report)
+		for _, finding := range report.Findings {
+			if !seen[finding.Rule] {
+				seen[finding.Rule] = true
+				analysis.Findings = append(analysis.Findings, finding)
+			}
+		}
+	}
+
+	// Some failures (wrong Java, missing mods) never get as far as writing a report.
+	// Only the output of this run counts.
+	history := i.GetHistory()
+	for n := len(history) - 1; n >= 0; n-- {
+		if history[n] == processStartMarker {
+			history = history[n+1:]
+			break
+		}
+	}
+	for _, finding := range AnalyzeCrash(strings.Join(history, "\n")) {
+		if !seen[finding.Rule] {
+			seen[finding.Rule] = true
+			analysis.Findings = append(analysis.Findings, finding)
+		}
+	}
+	return analysis
+}
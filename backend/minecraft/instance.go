@@ -2,11 +2,16 @@ package minecraft
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sync"
+	"syscall"
 
 	"runtime"
 	"strconv"
@@ -18,33 +23,75 @@ import (
 )
 
 type WSMessage struct {
-	Type string `json:"type"`
-	Data any    `json:"data"`
+	Type   string   `json:"type"`
+	Data   any      `json:"data"`
+	Cursor string   `json:"cursor,omitempty"` // Journal position of a log line
+	Log    *LogLine `json:"-"`                // Parsed log line, sent as data in structured mode
 }
 
 type ServerStats struct {
 	CpuUsage float64 `json:"cpu"`     // %
 	RamUsage uint64  `json:"ram"`     // Octets
 	RamMax   uint64  `json:"ram_max"` // Octets (Xmx)
+	Threads  int32   `json:"threads"`
+	// Last tick measurement, absent until one succeeded
+	Tick *TickStats `json:"tick,omitempty"`
 }
 
 type Instance struct {
 	ID       string
 	RunDir   string
 	JarName  string
+	JavaPath string // java binary, "java" from PATH by default
 	JavaArgs []string
+	// Arguments after "-jar <JarName>", e.g. --forceUpgrade
+	ServerArgs []string
+	Restart    RestartConfig
 
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
+	ReadyPattern   *regexp.Regexp
+	StartupTimeout time.Duration
+
+	// StopAndWait grace periods: StopTimeout after "stop", then TermGrace after SIGTERM before SIGKILL
+	StopTimeout time.Duration
+	TermGrace   time.Duration
+
+	// PreStart runs before every start, manual or automatic; an error aborts the start
+	PreStart func() error
+	// OnPlayerEvent is called for every join and leave of the live output
+	OnPlayerEvent func(PlayerEvent)
+
+	conn   net.Conn // Supervisor socket: stdin in, stdout out
+	pid    int      // JVM pid, 0 until the supervisor reports it
 	status core.ServerStatus
 	mu     sync.RWMutex
 
+	startedAt     time.Time
+	bootTime      time.Duration
+	startupTimer  *time.Timer
+	startFailure  string
+	stopRequested bool
+	replaying     bool          // Rebuilding state from the console log after a panel restart
+	exited        chan struct{} // Closed when the current process is gone
+	restart       restartState
+	stats         *ServerStats // Last sample of the running process, nil when stopped
+	tickMethod    TickMethod
+	tick          *TickStats // Last tick measurement, nil when stopped
+
+	rcon         *RCONClient // Pooled connection, opened on first use
+	rconMu       sync.Mutex  // Serializes dialing
+	rconAddr     string
+	rconPassword string
+
 	subscribers []chan WSMessage
 	subMu       sync.Mutex
 
-	logs []string
+	logs          []string
+	journal       *Journal
+	journalPaused bool // Protected by subMu, set while replaying
+	parser        LogParser
 
 	ConnectedPlayers map[string]bool
+	pendingPlayers   map[string]*pendingPlayer // Protected by playersMu
 	playersMu        sync.RWMutex
 }
 
@@ -53,14 +100,27 @@ func NewInstance(id string, runDir, jarName string) *Instance {
 		ID:               id,
 		RunDir:           runDir,
 		JarName:          jarName,
+		JavaPath:         "java",
 		JavaArgs:         []string{"-Xmx1G", "-Xms1G"},
+		Restart:          DefaultRestartConfig(),
+		ReadyPattern:     defaultReadyRegex,
+		StartupTimeout:   DefaultStartupTimeout,
+		StopTimeout:      DefaultStopTimeout,
+		TermGrace:        DefaultTermGrace,
 		status:           core.StatusStopped,
 		subscribers:      make([]chan WSMessage, 0),
 		logs:             make([]string, 0),
+		journal:          NewJournal(filepath.Join(runDir, SupervisorDir, journalDir), DefaultJournalConfig()),
 		ConnectedPlayers: make(map[string]bool),
+		pendingPlayers:   make(map[string]*pendingPlayer),
 	}
 }
 
+// Journal gives access to the on-disk console history
+func (i *Instance) Journal() *Journal {
+	return i.journal
+}
+
 func (i *Instance) Subscribe() chan WSMessage {
 	i.subMu.Lock()
 	defer i.subMu.Unlock()
@@ -96,6 +156,16 @@ func (i *Instance) GetStatus() core.ServerStatus {
 	return i.status
 }
 
+// GetUptime returns how long the current process has been up, 0 when it isn't running
+func (i *Instance) GetUptime() time.Duration {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.status != core.StatusRunning && i.status != core.StatusStarting {
+		return 0
+	}
+	return time.Since(i.startedAt)
+}
+
 func (i *Instance) SetStatus(status core.ServerStatus) {
 	i.mu.Lock()
 	i.status = status
@@ -104,6 +174,22 @@ func (i *Instance) SetStatus(status core.ServerStatus) {
 	i.broadcast(WSMessage{Type: "status", Data: string(status)})
 }
 
+// Stats returns the last resource sample, false when the process isn't being monitored
+func (i *Instance) Stats() (ServerStats, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.stats == nil {
+		return ServerStats{}, false
+	}
+	return *i.stats, true
+}
+
+func (i *Instance) PlayerCount() int {
+	i.playersMu.RLock()
+	defer i.playersMu.RUnlock()
+	return len(i.ConnectedPlayers)
+}
+
 // Helper to check if player is online
 func (i *Instance) IsPlayerOnline(name string) bool {
 	i.playersMu.RLock()
@@ -111,6 +197,18 @@ func (i *Instance) IsPlayerOnline(name string) bool {
 	return i.ConnectedPlayers[name]
 }
 
+func (i *Instance) SetJarName(jarName string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.JarName = jarName
+}
+
+func (i *Instance) SetJavaPath(path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.JavaPath = path
+}
+
 func (i *Instance) SetRAM(mb int) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -134,6 +232,8 @@ func (i *Instance) SetRAM(mb int) {
 	i.JavaArgs = newArgs
 }
 
+const processStartMarker = "--- PROCESS START ---"
+
 var (
 	joinRegex  = regexp.MustCompile(`]: (\w+) joined the game`)
 	leaveRegex = regexp.MustCompile(`]: (\w+) left the game`)
@@ -141,116 +241,489 @@ var (
 
 func (i *Instance) Start() error {
 	i.mu.Lock()
-	if i.status != core.StatusStopped {
+	if i.status != core.StatusStopped && i.status != core.StatusCrashed {
 		i.mu.Unlock()
 		return fmt.Errorf("server is already running")
 	}
+	i.cancelPendingRestart()
+	previous := i.status
 	i.status = core.StatusStarting
+	i.stopRequested = false
+	i.startFailure = ""
+	i.bootTime = 0
+	preStart := i.PreStart
 	i.mu.Unlock()
 
+	if preStart != nil {
+		if err := preStart(); err != nil {
+			i.SetStatus(previous)
+			return err
+		}
+	}
+
+	// Read after PreStart, which may update them
+	i.mu.RLock()
+	javaPath := i.JavaPath
+	jarName := i.JarName
+	javaArgs := append([]string{}, i.JavaArgs...)
+	serverArgs := append([]string{}, i.ServerArgs...)
+	i.mu.RUnlock()
+
 	i.broadcast(WSMessage{Type: "status", Data: string(core.StatusStarting)})
 
-	args := append(i.JavaArgs, "-jar", i.JarName, "nogui")
-	i.cmd = exec.Command("java", args...)
-	i.cmd.Dir = i.RunDir
+	// Reset players on start
+	i.playersMu.Lock()
+	i.ConnectedPlayers = make(map[string]bool)
+	i.pendingPlayers = make(map[string]*pendingPlayer)
+	i.playersMu.Unlock()
 
-	stdout, err := i.cmd.StdoutPipe()
+	conn, err := i.spawnSupervisor(javaPath, jarName, javaArgs, serverArgs)
 	if err != nil {
 		i.SetStatus(core.StatusStopped)
 		return err
 	}
-	i.cmd.Stderr = i.cmd.Stdout
 
-	stdin, err := i.cmd.StdinPipe()
+	// Logged before attaching so it always precedes the output of this run
+	i.broadcastLog(processStartMarker)
+
+	// Stay in STARTING until the ready line shows up (see checkReady)
+	i.attach(conn, time.Now(), 0)
+	i.mu.Lock()
+	i.startupTimer = time.AfterFunc(i.StartupTimeout, i.startupTimedOut)
+	i.mu.Unlock()
+
+	return nil
+}
+
+// spawnSupervisor launches "crafteur supervise" in its own session and connects to it.
+// The supervisor only starts java once this first connection is made.
+func (i *Instance) spawnSupervisor(javaPath, jarName string, javaArgs, serverArgs []string) (net.Conn, error) {
+	self, err := os.Executable()
 	if err != nil {
-		i.SetStatus(core.StatusStopped)
-		return err
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(i.RunDir, SupervisorDir), 0755); err != nil {
+		return nil, err
+	}
+	output, err := os.Create(supervisorPath(i.RunDir, supervisorOutput))
+	if err != nil {
+		return nil, err
 	}
-	i.stdin = stdin
+	defer output.Close()
+
+	args := []string{"supervise", "--", javaPath}
+	args = append(args, javaArgs...)
+	args = append(args, "-jar", jarName, "nogui")
+	args = append(args, serverArgs...)
+
+	cmd := exec.Command(self, args...)
+	cmd.Dir = i.RunDir
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// Reap it when it exits; after a panel restart it is re-parented to init instead
+	go cmd.Wait()
 
-	if err := i.cmd.Start(); err != nil {
-		i.SetStatus(core.StatusStopped)
-		return err
+	conn, err := dialSupervisor(i.RunDir, 10*time.Second)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("supervisor unreachable: %w", err)
+	}
+	return conn, nil
+}
+
+func dialSupervisor(runDir string, timeout time.Duration) (net.Conn, error) {
+	sockPath := supervisorPath(runDir, supervisorSocket)
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("unix", sockPath)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(100 * time.Millisecond)
 	}
+}
 
-	i.SetStatus(core.StatusRunning)
-	i.broadcastLog("--- PROCESS START ---")
+// Reattach reconnects to a server left running by a previous panel process, rebuilding
+// the console history, players and status from the supervisor's console log.
+func (i *Instance) Reattach() (bool, error) {
+	statePath := supervisorPath(i.RunDir, supervisorState)
+	var state SupervisorState
+	if err := readJSONFile(statePath, &state); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !processAlive(state.SupervisorPID) || !processAlive(state.JavaPID) {
+		os.Remove(statePath)
+		return false, nil
+	}
 
-	go i.monitorProcess(stdout)
-	go i.startMonitoring()
+	conn, err := dialSupervisor(i.RunDir, 2*time.Second)
+	if err != nil {
+		return false, err
+	}
 
-	return nil
+	i.mu.Lock()
+	i.status = core.StatusStarting
+	i.stopRequested = false
+	i.startFailure = ""
+	i.startedAt = state.StartedAt
+	i.mu.Unlock()
+
+	i.replayConsoleLog()
+
+	i.attach(conn, state.StartedAt, state.JavaPID)
+	i.mu.Lock()
+	if i.status == core.StatusStarting {
+		remaining := i.StartupTimeout - time.Since(state.StartedAt)
+		if remaining < 0 {
+			remaining = 0
+		}
+		i.startupTimer = time.AfterFunc(remaining, i.startupTimedOut)
+	}
+	status := i.status
+	i.mu.Unlock()
+
+	i.broadcast(WSMessage{Type: "status", Data: string(status)})
+	i.broadcastLog("--- REATTACHED TO RUNNING PROCESS ---")
+	return true, nil
 }
 
-func (i *Instance) monitorProcess(stdout io.Reader) {
-	// Reset players on start
+// replayConsoleLog feeds the current run's output back through the line handlers
+func (i *Instance) replayConsoleLog() {
+	i.mu.Lock()
+	i.replaying = true
+	i.mu.Unlock()
+	// Those lines were journaled by the previous panel
+	i.subMu.Lock()
+	i.journalPaused = true
+	i.subMu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		i.replaying = false
+		i.mu.Unlock()
+		i.subMu.Lock()
+		i.journalPaused = false
+		i.subMu.Unlock()
+	}()
+
+	var readyLine string
+	scanConsoleLog(i.RunDir, func(text string) {
+		if i.handleLine(text) {
+			readyLine = text
+		}
+	})
+
+	// The measured boot time is lost with the old panel, use the one the server printed
+	if matches := defaultReadyRegex.FindStringSubmatch(readyLine); len(matches) > 1 {
+		if secs, err := strconv.ParseFloat(strings.ReplaceAll(matches[1], ",", "."), 64); err == nil {
+			i.mu.Lock()
+			i.bootTime = time.Duration(secs * float64(time.Second))
+			i.mu.Unlock()
+		}
+	}
+}
+
+// scanConsoleLog calls fn for every line of the current run still in the console log segments
+func scanConsoleLog(runDir string, fn func(string)) {
+	for _, path := range consoleLogFiles(runDir) {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fn(scanner.Text())
+		}
+		file.Close()
+	}
+}
+
+// resync reconnects to a supervisor that dropped this panel for falling too far behind the
+// output, while the server keeps running. Readiness and players are rebuilt from the console
+// log, the missed lines themselves are only in the log.
+func (i *Instance) resync(exited chan struct{}) bool {
+	var state SupervisorState
+	if err := readJSONFile(supervisorPath(i.RunDir, supervisorState), &state); err != nil {
+		return false
+	}
+	if !processAlive(state.SupervisorPID) || !processAlive(state.JavaPID) {
+		return false
+	}
+	conn, err := dialSupervisor(i.RunDir, 2*time.Second)
+	if err != nil {
+		return false
+	}
+
+	before := i.OnlinePlayers()
 	i.playersMu.Lock()
 	i.ConnectedPlayers = make(map[string]bool)
+	i.pendingPlayers = make(map[string]*pendingPlayer)
 	i.playersMu.Unlock()
 
+	i.mu.Lock()
+	if i.conn != nil {
+		i.conn.Close()
+	}
+	i.conn = conn
+	i.replaying = true
+	i.mu.Unlock()
+	scanConsoleLog(i.RunDir, func(text string) {
+		i.checkReady(text)
+		i.trackPlayer(text)
+	})
+	i.mu.Lock()
+	i.replaying = false
+	i.mu.Unlock()
+
+	// Report the joins and leaves that happened meanwhile
+	i.reportPlayerChanges(before)
+
+	i.broadcast(WSMessage{Type: "status", Data: string(i.GetStatus())})
+	i.broadcastLog("--- CONSOLE FELL BEHIND, RESYNCED FROM THE SUPERVISOR LOG ---")
+	go i.monitorProcess(conn, exited)
+	return true
+}
+
+func (i *Instance) attach(conn net.Conn, startedAt time.Time, pid int) {
+	exited := make(chan struct{})
+	i.mu.Lock()
+	i.conn = conn
+	i.pid = pid
+	i.exited = exited
+	i.startedAt = startedAt
+	i.mu.Unlock()
+
+	go i.monitorProcess(conn, exited)
+	go i.startMonitoring()
+	go i.monitorTicks(exited)
+}
+
+// handleLine processes one line of server output, returning true if it made the server ready
+func (i *Instance) handleLine(text string) bool {
+	i.broadcastLog(text)
+	ready := i.checkReady(text)
+
+	// Joins and leaves
+	i.trackPlayer(text)
+
+	return ready
+}
+
+// javaPID returns the pid of the JVM, read from the supervisor state once it is known
+func (i *Instance) javaPID() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.pid == 0 && i.conn != nil {
+		var state SupervisorState
+		if err := readJSONFile(supervisorPath(i.RunDir, supervisorState), &state); err == nil {
+			i.pid = state.JavaPID
+		}
+	}
+	return i.pid
+}
+
+func (i *Instance) monitorProcess(stdout io.Reader, exited chan struct{}) {
 	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		text := scanner.Text()
-		i.broadcastLog(text)
-
-		// Parse Join
-		if matches := joinRegex.FindStringSubmatch(text); len(matches) > 1 {
-			player := matches[1]
-			i.playersMu.Lock()
-			i.ConnectedPlayers[player] = true
-			i.playersMu.Unlock()
-		}
+		i.handleLine(scanner.Text())
+	}
 
-		// Parse Leave
-		if matches := leaveRegex.FindStringSubmatch(text); len(matches) > 1 {
-			player := matches[1]
-			i.playersMu.Lock()
-			delete(i.ConnectedPlayers, player)
-			i.playersMu.Unlock()
-		}
+	// The supervisor writes exit.json before closing the connection
+	exitCode := -1
+	reason := "supervisor connection lost"
+	var result SupervisorExit
+	if err := readJSONFile(supervisorPath(i.RunDir, supervisorExit), &result); err == nil {
+		exitCode = result.ExitCode
+		reason = result.Error
+	} else if i.resync(exited) {
+		return
 	}
 
-	if err := i.cmd.Wait(); err != nil {
-		i.broadcastLog(fmt.Sprintf("--- CRASH/STOP ERROR: %v ---", err))
-	} else {
+	// Clear players on stop
+	i.clearPlayers("Server stopped")
+
+	i.mu.Lock()
+	stopRequested := i.stopRequested
+	uptime := time.Since(i.startedAt)
+	if i.startupTimer != nil {
+		i.startupTimer.Stop()
+		i.startupTimer = nil
+	}
+	if i.startFailure != "" {
+		reason = i.startFailure
+	}
+	if i.conn != nil {
+		i.conn.Close()
+	}
+	i.conn = nil
+	i.pid = 0
+	i.closeRCONLocked()
+	i.mu.Unlock()
+
+	switch {
+	case exitCode == 0:
 		i.broadcastLog("--- PROCESS STOPPED GRACEFULLY ---")
+	case stopRequested:
+		i.broadcastLog(fmt.Sprintf("--- PROCESS STOPPED (exit code %d) ---", exitCode))
+	default:
+		i.broadcastLog(fmt.Sprintf("--- CRASH (exit code %d): %s ---", exitCode, reason))
 	}
 
-	// Clear players on stop
-	i.playersMu.Lock()
-	i.ConnectedPlayers = make(map[string]bool)
-	i.playersMu.Unlock()
+	i.handleExit(exitCode, stopRequested, uptime, reason)
 
-	i.SetStatus(core.StatusStopped)
-	i.cmd = nil
-	i.stdin = nil
+	i.mu.Lock()
+	if i.exited == exited {
+		i.exited = nil
+	}
+	i.mu.Unlock()
+	close(exited)
 }
 
 func (i *Instance) Stop() error {
-	if i.GetStatus() == core.StatusStopped {
+	i.mu.Lock()
+	i.stopRequested = true
+	i.cancelPendingRestart()
+	status := i.status
+	i.mu.Unlock()
+
+	if status == core.StatusStopped {
+		return nil
+	}
+	if status == core.StatusCrashed {
+		// Nothing is running, only a pending restart to cancel
+		i.SetStatus(core.StatusStopped)
 		return nil
 	}
 
-	err := i.SendCommand("stop")
+	// Written directly so a server still loading its world can be stopped too
+	err := i.writeStdin("stop")
 	if err == nil {
 		i.SetStatus(core.StatusStopping)
 		return nil
 	}
 
-	if i.cmd != nil && i.cmd.Process != nil {
-		return i.cmd.Process.Kill()
+	if pid := i.javaPID(); pid != 0 {
+		return syscall.Kill(pid, syscall.SIGKILL)
 	}
 	return fmt.Errorf("instance not found")
 }
 
+const (
+	DefaultStopTimeout = 30 * time.Second
+	DefaultTermGrace   = 10 * time.Second
+)
+
+// SetStopTimeouts sets the grace periods of StopAndWait, zero restores a default
+func (i *Instance) SetStopTimeouts(stopTimeout, termGrace time.Duration) {
+	if stopTimeout <= 0 {
+		stopTimeout = DefaultStopTimeout
+	}
+	if termGrace <= 0 {
+		termGrace = DefaultTermGrace
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.StopTimeout = stopTimeout
+	i.TermGrace = termGrace
+}
+
+// StopAndWait stops the server and blocks until the process is gone. If it is still
+// alive after timeout (0 = StopTimeout) it gets a SIGTERM, then a SIGKILL after TermGrace.
+func (i *Instance) StopAndWait(ctx context.Context, timeout time.Duration) error {
+	i.mu.RLock()
+	exited := i.exited
+	termGrace := i.TermGrace
+	if timeout <= 0 {
+		timeout = i.StopTimeout
+	}
+	i.mu.RUnlock()
+
+	if err := i.Stop(); err != nil {
+		return err
+	}
+	if exited == nil {
+		return nil
+	}
+
+	if waitExit(ctx, exited, timeout) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.broadcastLog(fmt.Sprintf("--- STOP TIMEOUT after %s, sending SIGTERM ---", timeout))
+	i.signal(syscall.SIGTERM)
+	if waitExit(ctx, exited, termGrace) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.broadcastLog(fmt.Sprintf("--- STILL ALIVE after %s, sending SIGKILL ---", termGrace))
+	i.signal(syscall.SIGKILL)
+	if waitExit(ctx, exited, termGrace) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("process did not exit after SIGKILL")
+}
+
+func (i *Instance) signal(sig syscall.Signal) {
+	if pid := i.javaPID(); pid != 0 {
+		syscall.Kill(pid, sig)
+	}
+}
+
+func waitExit(ctx context.Context, exited chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func (i *Instance) SendCommand(cmd string) error {
+	switch i.GetStatus() {
+	case core.StatusRunning:
+	case core.StatusStarting:
+		return fmt.Errorf("server is still starting")
+	default:
+		return fmt.Errorf("server stopped")
+	}
+	return i.writeStdin(cmd)
+}
+
+func (i *Instance) writeStdin(cmd string) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	if i.stdin == nil || i.status != core.StatusRunning {
+	if i.conn == nil {
 		return fmt.Errorf("server stopped")
 	}
-	_, err := io.WriteString(i.stdin, cmd+"\n")
+	_, err := io.WriteString(i.conn, cmd+"\n")
 
 	return err
 }
@@ -264,7 +737,11 @@ func (i *Instance) broadcastLog(msg string) {
 		i.logs = i.logs[1:]
 	}
 
-	message := WSMessage{Type: "log", Data: msg}
+	parsed := i.parser.Parse(msg)
+	message := WSMessage{Type: "log", Data: msg, Log: &parsed}
+	if !i.journalPaused {
+		message.Cursor, _ = i.journal.Append(time.Now(), msg)
+	}
 
 	for _, ch := range i.subscribers {
 		select {
@@ -287,12 +764,27 @@ func (i *Instance) broadcast(msg WSMessage) {
 }
 
 func (i *Instance) startMonitoring() {
-	i.mu.Lock()
-	if i.cmd == nil || i.cmd.Process == nil {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	defer func() {
+		i.mu.Lock()
+		i.stats = nil
 		i.mu.Unlock()
-		return
+	}()
+
+	// The JVM pid is only known once the supervisor has launched it
+	var javaPID int
+	for javaPID == 0 {
+		if status := i.GetStatus(); status != core.StatusRunning && status != core.StatusStarting {
+			return
+		}
+		if javaPID = i.javaPID(); javaPID == 0 {
+			<-ticker.C
+		}
 	}
-	pid := int32(i.cmd.Process.Pid)
+	pid := int32(javaPID)
+
+	i.mu.Lock()
 	maxRam := i.parseMaxRam()
 	i.mu.Unlock()
 
@@ -301,11 +793,8 @@ func (i *Instance) startMonitoring() {
 		return
 	}
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
 	for {
-		if i.GetStatus() != core.StatusRunning {
+		if status := i.GetStatus(); status != core.StatusRunning && status != core.StatusStarting {
 			return
 		}
 
@@ -328,12 +817,22 @@ func (i *Instance) startMonitoring() {
 				ramUsage = mem.RSS
 			}
 
+			threads, _ := proc.NumThreads()
+
 			stats := ServerStats{
 				CpuUsage: cpuNormalized,
 				RamUsage: ramUsage,
 				RamMax:   maxRam,
+				Threads:  threads,
+			}
+			if tick, ok := i.TickStats(); ok {
+				stats.Tick = &tick
 			}
 
+			i.mu.Lock()
+			i.stats = &stats
+			i.mu.Unlock()
+
 			i.broadcast(WSMessage{Type: "stats", Data: stats})
 		}
 	}
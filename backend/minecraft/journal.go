@@ -0,0 +1,428 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The journal keeps every console line on disk, in <RunDir>/.crafteur/journal/<seq>.log segments.
+// Each line is "<RFC3339Nano time>\t<text>". A cursor "<seq>-<offset>" points right after an
+// entry, so reading from a cursor resumes with the next line.
+const journalDir = "journal"
+
+type JournalConfig struct {
+	MaxSegmentSize int64         // Rotate when the current segment grows past this
+	MaxSegmentAge  time.Duration // Rotate when the current segment is older than this
+	MaxSegments    int           // Oldest segments are deleted beyond this count
+	Retention      time.Duration // Segments last written before now-Retention are deleted
+}
+
+func DefaultJournalConfig() JournalConfig {
+	return JournalConfig{
+		MaxSegmentSize: 10 * 1024 * 1024,
+		MaxSegmentAge:  24 * time.Hour,
+		MaxSegments:    20,
+		Retention:      14 * 24 * time.Hour,
+	}
+}
+
+type JournalEntry struct {
+	Cursor string    `json:"cursor"`
+	Time   time.Time `json:"time"`
+	Level  string    `json:"level,omitempty"`
+	Line   string    `json:"line"`
+}
+
+type JournalQuery struct {
+	Cursor string // Only entries after this cursor
+	Since  time.Time
+	Until  time.Time
+	Search string // Case-insensitive substring
+	Level  string // INFO, WARN, ERROR...
+	Limit  int
+}
+
+type JournalPage struct {
+	Entries []JournalEntry `json:"entries"`
+	Next    string         `json:"next,omitempty"` // Cursor of the next page, empty on the last one
+}
+
+type Journal struct {
+	dir    string
+	config JournalConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	seq      int
+	size     int64
+	openedAt time.Time
+}
+
+func NewJournal(dir string, config JournalConfig) *Journal {
+	return &Journal{dir: dir, config: config}
+}
+
+// Append writes a line and returns its cursor. Errors only cost the line its persistence.
+func (j *Journal) Append(t time.Time, line string) (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil || j.size >= j.config.MaxSegmentSize || time.Since(j.openedAt) >= j.config.MaxSegmentAge {
+		if err := j.rotate(); err != nil {
+			return "", err
+		}
+	}
+
+	record := t.Format(time.RFC3339Nano) + "\t" + strings.ReplaceAll(line, "\n", " ") + "\n"
+	n, err := io.WriteString(j.file, record)
+	j.size += int64(n)
+	if err != nil {
+		return "", err
+	}
+	return formatCursor(j.seq, j.size), nil
+}
+
+// rotate opens a new segment and prunes old ones. Caller must hold j.mu.
+func (j *Journal) rotate() error {
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return err
+	}
+
+	segments, err := j.segments()
+	if err != nil {
+		return err
+	}
+	if len(segments) > 0 && segments[len(segments)-1] > j.seq {
+		j.seq = segments[len(segments)-1]
+	}
+	j.seq++
+
+	file, err := os.OpenFile(j.segmentPath(j.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	j.file = file
+	j.size = 0
+	j.openedAt = time.Now()
+
+	j.prune(append(segments, j.seq))
+	return nil
+}
+
+func (j *Journal) prune(segments []int) {
+	for idx, seq := range segments {
+		if seq == j.seq {
+			continue
+		}
+		tooMany := j.config.MaxSegments > 0 && len(segments)-idx > j.config.MaxSegments
+		expired := false
+		if info, err := os.Stat(j.segmentPath(seq)); err == nil && j.config.Retention > 0 {
+			expired = time.Since(info.ModTime()) > j.config.Retention
+		}
+		if tooMany || expired {
+			os.Remove(j.segmentPath(seq))
+		}
+	}
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) segmentPath(seq int) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%08d.log", seq))
+}
+
+// segments returns the sequence numbers on disk, oldest first
+func (j *Journal) segments() ([]int, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	seqs := []int{}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".log")
+		if seq, err := strconv.Atoi(name); err == nil && name != e.Name() {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Ints(seqs)
+	return seqs, nil
+}
+
+// Query returns the entries after q.Cursor matching the filters, oldest first
+func (j *Journal) Query(q JournalQuery) (*JournalPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 500
+	}
+	fromSeq, fromOffset := 0, int64(0)
+	if q.Cursor != "" {
+		var err error
+		if fromSeq, fromOffset, err = ParseCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	j.mu.Lock()
+	segments, err := j.segments()
+	j.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &JournalPage{Entries: []JournalEntry{}}
+	for _, seq := range segments {
+		if seq < fromSeq {
+			continue
+		}
+		offset := int64(0)
+		if seq == fromSeq {
+			offset = fromOffset
+		}
+		// Segments last written before the range can be skipped without reading them
+		if !q.Since.IsZero() {
+			if info, err := os.Stat(j.segmentPath(seq)); err == nil && info.ModTime().Before(q.Since) {
+				continue
+			}
+		}
+
+		full, past := false, false
+		err := j.scanSegment(seq, offset, func(e JournalEntry) bool {
+			if !q.Until.IsZero() && e.Time.After(q.Until) {
+				past = true
+				return false
+			}
+			if !q.matches(e) {
+				return true
+			}
+			if len(page.Entries) == q.Limit {
+				full = true
+				return false
+			}
+			page.Entries = append(page.Entries, e)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if full {
+			page.Next = page.Entries[len(page.Entries)-1].Cursor
+			break
+		}
+		if past {
+			break
+		}
+	}
+	return page, nil
+}
+
+// Tail returns the last n entries matching the filters (cursor and limit are ignored), oldest
+// first. Segments are read backwards from the newest one, stopping once n entries are found.
+func (j *Journal) Tail(n int, q JournalQuery) ([]JournalEntry, error) {
+	j.mu.Lock()
+	segments, err := j.segments()
+	j.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []JournalEntry{}
+	for idx := len(segments) - 1; idx >= 0 && len(result) < n; idx-- {
+		// A segment last written before the range holds nothing in it, nor do the older ones
+		if !q.Since.IsZero() {
+			if info, err := os.Stat(j.segmentPath(segments[idx])); err == nil && info.ModTime().Before(q.Since) {
+				break
+			}
+		}
+
+		before := false
+		err := j.scanSegmentReverse(segments[idx], func(e JournalEntry) bool {
+			if !q.Until.IsZero() && e.Time.After(q.Until) {
+				return true
+			}
+			if !q.Since.IsZero() && e.Time.Before(q.Since) {
+				before = true
+				return false
+			}
+			if q.matches(e) {
+				result = append(result, e)
+			}
+			return len(result) < n
+		})
+		if err != nil {
+			return nil, err
+		}
+		if before {
+			break
+		}
+	}
+	slices.Reverse(result)
+	return result, nil
+}
+
+func (q *JournalQuery) matches(e JournalEntry) bool {
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if q.Level != "" && !strings.EqualFold(e.Level, q.Level) {
+		return false
+	}
+	if q.Search != "" && !strings.Contains(strings.ToLower(e.Line), strings.ToLower(q.Search)) {
+		return false
+	}
+	return true
+}
+
+// scanSegment calls fn for every entry from offset until it returns false
+func (j *Journal) scanSegment(seq int, offset int64, fn func(JournalEntry) bool) error {
+	file, err := os.Open(j.segmentPath(seq))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Pruned meanwhile
+		}
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		raw, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial last line is still being written
+			return nil
+		}
+		offset += int64(len(raw))
+
+		if entry, ok := parseJournalLine(seq, offset, raw); ok && !fn(entry) {
+			return nil
+		}
+	}
+}
+
+// scanSegmentReverse calls fn for every entry of a segment, newest first, until it returns false.
+// The segment is read by chunks from its end, so only what fn needs is read.
+func (j *Journal) scanSegmentReverse(seq int, fn func(JournalEntry) bool) error {
+	file, err := os.Open(j.segmentPath(seq))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Pruned meanwhile
+		}
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// data holds whole lines, from the chunk read last to the end of the lines left to visit
+	pos := info.Size()
+	var data []byte
+	complete := false
+	for {
+		for complete && len(data) > 0 {
+			start := bytes.LastIndexByte(data[:len(data)-1], '\n') + 1
+			if start == 0 && pos > 0 {
+				break // The line begins in the previous chunk
+			}
+			entry, ok := parseJournalLine(seq, pos+int64(len(data)), string(data[start:]))
+			data = data[:start]
+			if ok && !fn(entry) {
+				return nil
+			}
+		}
+		if pos == 0 {
+			return nil
+		}
+
+		size := min(pos, 64*1024)
+		chunk := make([]byte, int(size)+len(data))
+		if _, err := file.ReadAt(chunk[:size], pos-size); err != nil {
+			return err
+		}
+		copy(chunk[size:], data)
+		data, pos = chunk, pos-size
+
+		if !complete {
+			// A partial last line is still being written
+			if end := bytes.LastIndexByte(data, '\n'); end >= 0 {
+				data, complete = data[:end+1], true
+			}
+		}
+	}
+}
+
+// parseJournalLine parses a "<time>\t<text>\n" record, offset being where the next one starts
+func parseJournalLine(seq int, offset int64, raw string) (JournalEntry, bool) {
+	stamp, line, ok := strings.Cut(strings.TrimSuffix(raw, "\n"), "\t")
+	if !ok {
+		return JournalEntry{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return JournalEntry{}, false
+	}
+	return JournalEntry{
+		Cursor: formatCursor(seq, offset),
+		Time:   t,
+		Level:  LineLevel(line),
+		Line:   line,
+	}, true
+}
+
+func formatCursor(seq int, offset int64) string {
+	return fmt.Sprintf("%d-%d", seq, offset)
+}
+
+func ParseCursor(cursor string) (int, int64, error) {
+	seqStr, offsetStr, ok := strings.Cut(cursor, "-")
+	seq, err1 := strconv.Atoi(seqStr)
+	offset, err2 := strconv.ParseInt(offsetStr, 10, 64)
+	if !ok || err1 != nil || err2 != nil || seq < 0 || offset < 0 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return seq, offset, nil
+}
+
+// CursorAfter reports whether cursor a points after cursor b
+func CursorAfter(a, b string) bool {
+	seqA, offA, errA := ParseCursor(a)
+	seqB, offB, errB := ParseCursor(b)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return seqA > seqB || (seqA == seqB && offA > offB)
+}
+
+// LineLevel returns the log level of a console line, empty when it has none
+func LineLevel(line string) string {
+	return ParseLogLine(line).Level
+}
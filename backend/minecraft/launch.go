@@ -0,0 +1,149 @@
+package minecraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+type JVMPreset struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Args        []string `json:"args"`
+}
+
+// JVMPresets are ready-made flag sets, memory flags excluded since they come from the RAM setting
+var JVMPresets = []JVMPreset{
+	{
+		Name:        "aikar",
+		Description: "Aikar's G1GC flags, the usual recommendation for Paper servers",
+		Args: []string{
+			"-XX:+UseG1GC",
+			"-XX:+ParallelRefProcEnabled",
+			"-XX:MaxGCPauseMillis=200",
+			"-XX:+UnlockExperimentalVMOptions",
+			"-XX:+DisableExplicitGC",
+			"-XX:+AlwaysPreTouch",
+			"-XX:G1NewSizePercent=30",
+			"-XX:G1MaxNewSizePercent=40",
+			"-XX:G1HeapRegionSize=8M",
+			"-XX:G1ReservePercent=20",
+			"-XX:G1HeapWastePercent=5",
+			"-XX:G1MixedGCCountTarget=4",
+			"-XX:InitiatingHeapOccupancyPercent=15",
+			"-XX:G1MixedGCLiveThresholdPercent=90",
+			"-XX:G1RSetUpdatingPauseTimePercent=5",
+			"-XX:SurvivorRatio=32",
+			"-XX:+PerfDisableSharedMem",
+			"-XX:MaxTenuringThreshold=1",
+			"-Dusing.aikars.flags=https://mcflags.emc.gs",
+			"-Daikars.new.flags=true",
+		},
+	},
+	{
+		Name:        "zgc",
+		Description: "Generational ZGC for large heaps, needs Java 21",
+		Args: []string{
+			"-XX:+UseZGC",
+			"-XX:+ZGenerational",
+			"-XX:+AlwaysPreTouch",
+			"-XX:+DisableExplicitGC",
+			"-XX:+PerfDisableSharedMem",
+		},
+	},
+	{
+		Name:        "minimal",
+		Description: "JVM defaults, only forcing UTF-8",
+		Args: []string{
+			"-Dfile.encoding=UTF-8",
+		},
+	},
+}
+
+func FindJVMPreset(name string) (*JVMPreset, bool) {
+	for i := range JVMPresets {
+		if JVMPresets[i].Name == name {
+			return &JVMPresets[i], true
+		}
+	}
+	return nil, false
+}
+
+// Memory is driven by the server's RAM setting, these would silently override it
+var memoryFlagPrefixes = []string{
+	"-Xmx", "-Xms", "-XX:MaxHeapSize", "-XX:InitialHeapSize",
+	"-XX:MaxRAM", "-XX:MaxRAMPercentage", "-XX:InitialRAMPercentage", "-XX:MinRAMPercentage",
+}
+
+// Flags that run arbitrary commands or replace what the panel launches
+var forbiddenFlagPrefixes = []string{
+	"-XX:OnError", "-XX:OnOutOfMemoryError",
+	"-jar", "-cp", "-classpath", "--class-path", "-version", "--version", "-help", "--help",
+}
+
+var garbageCollectors = []string{
+	"-XX:+UseG1GC", "-XX:+UseZGC", "-XX:+UseShenandoahGC", "-XX:+UseParallelGC", "-XX:+UseSerialGC", "-XX:+UseEpsilonGC",
+}
+
+// ValidateJVMArgs rejects flags that conflict with the panel's memory management or are unsafe
+func ValidateJVMArgs(args []string) error {
+	gcs := []string{}
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("empty JVM argument")
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("invalid JVM argument %q: must start with '-'", arg)
+		}
+		for _, prefix := range memoryFlagPrefixes {
+			if strings.HasPrefix(arg, prefix) {
+				return fmt.Errorf("%s conflicts with the RAM setting, change the server RAM instead", arg)
+			}
+		}
+		for _, prefix := range forbiddenFlagPrefixes {
+			if arg == prefix || strings.HasPrefix(arg, prefix+"=") || strings.HasPrefix(arg, prefix+":") {
+				return fmt.Errorf("JVM argument %s is not allowed", arg)
+			}
+		}
+		for _, gc := range garbageCollectors {
+			if arg == gc {
+				gcs = append(gcs, gc)
+			}
+		}
+	}
+	if len(gcs) > 1 {
+		return fmt.Errorf("conflicting garbage collectors: %s", strings.Join(gcs, ", "))
+	}
+	return nil
+}
+
+// ValidateServerArgs checks the arguments given to the server after the jar
+func ValidateServerArgs(args []string) error {
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("empty server argument")
+		}
+		switch {
+		case arg == "nogui" || arg == "--nogui":
+			return fmt.Errorf("nogui is always passed by the panel")
+		case arg == "--port" || strings.HasPrefix(arg, "--port="), arg == "-p":
+			return fmt.Errorf("the port is managed by the server settings")
+		}
+	}
+	return nil
+}
+
+// SetLaunchArgs sets the extra JVM flags (memory flags from SetRAM are kept) and the server arguments
+func (i *Instance) SetLaunchArgs(jvmArgs, serverArgs []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	newArgs := make([]string, 0, len(jvmArgs)+2)
+	newArgs = append(newArgs, jvmArgs...)
+	for _, arg := range i.JavaArgs {
+		if strings.HasPrefix(arg, "-Xmx") || strings.HasPrefix(arg, "-Xms") {
+			newArgs = append(newArgs, arg)
+		}
+	}
+	i.JavaArgs = newArgs
+	i.ServerArgs = append([]string{}, serverArgs...)
+}
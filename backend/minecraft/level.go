@@ -0,0 +1,141 @@
+package minecraft
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// LevelInfo is the metadata of a world read from its level.dat
+type LevelInfo struct {
+	LevelName   string     `json:"level_name"`
+	Seed        int64      `json:"seed,string"` // Strings since JavaScript numbers can't hold every seed
+	GameType    string     `json:"game_type"`
+	Difficulty  string     `json:"difficulty"`
+	Hardcore    bool       `json:"hardcore"`
+	DataVersion int        `json:"data_version"`
+	VersionName string     `json:"version_name"`
+	Snapshot    bool       `json:"snapshot"`
+	LastPlayed  int64      `json:"last_played"` // Unix seconds
+	Spawn       LevelSpawn `json:"spawn"`
+	Datapacks   []string   `json:"datapacks"`
+}
+
+type LevelSpawn struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Z         int    `json:"z"`
+	Dimension string `json:"dimension"`
+}
+
+var gameTypes = []string{"survival", "creative", "adventure", "spectator"}
+var difficulties = []string{"peaceful", "easy", "normal", "hard"}
+
+// ReadLevel reads <world>/level.dat
+func ReadLevel(worldDir string) (*LevelInfo, error) {
+	root, err := ReadNBTFile(filepath.Join(worldDir, "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	data := root.Compound("Data")
+	if data == nil {
+		return nil, fmt.Errorf("level.dat has no Data compound")
+	}
+
+	level := &LevelInfo{
+		LevelName:   data.String("LevelName"),
+		Seed:        data.Int("RandomSeed"),
+		GameType:    enumName(gameTypes, data.Int("GameType")),
+		Difficulty:  enumName(difficulties, data.Int("Difficulty")),
+		Hardcore:    data.Bool("hardcore"),
+		DataVersion: int(data.Int("DataVersion")),
+		LastPlayed:  data.Int("LastPlayed") / 1000,
+		Spawn: LevelSpawn{
+			X:         int(data.Int("SpawnX")),
+			Y:         int(data.Int("SpawnY")),
+			Z:         int(data.Int("SpawnZ")),
+			Dimension: "minecraft:overworld",
+		},
+		Datapacks: []string{},
+	}
+
+	// Since 1.16 the seed lives with the generator settings
+	if gen := data.Compound("WorldGenSettings"); gen.Has("seed") {
+		level.Seed = gen.Int("seed")
+	}
+	if version := data.Compound("Version"); version != nil {
+		level.VersionName = version.String("Name")
+		level.Snapshot = version.Bool("Snapshot")
+	}
+	// Recent versions store the spawn as a compound with the dimension
+	if spawn := data.Compound("spawn"); spawn != nil {
+		if pos, ok := spawn["pos"].([]int32); ok && len(pos) == 3 {
+			level.Spawn.X, level.Spawn.Y, level.Spawn.Z = int(pos[0]), int(pos[1]), int(pos[2])
+		}
+		if dim := spawn.String("dimension"); dim != "" {
+			level.Spawn.Dimension = dim
+		}
+	}
+	for _, pack := range data.Compound("DataPacks").List("Enabled") {
+		if name, ok := pack.(string); ok {
+			level.Datapacks = append(level.Datapacks, name)
+		}
+	}
+
+	return level, nil
+}
+
+func enumName(names []string, value int64) string {
+	if value < 0 || value >= int64(len(names)) {
+		return fmt.Sprintf("unknown (%d)", value)
+	}
+	return names[value]
+}
+
+// JarDataVersion reads the world DataVersion of a server jar from its version.json. Vanilla and
+// bundler jars have it, loaders only wrap the vanilla jar so callers should try it as well.
+func JarDataVersion(jarPath string) (int, string, error) {
+	zr, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer zr.Close()
+
+	file, err := zr.Open("version.json")
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	var version struct {
+		Name         string `json:"name"`
+		WorldVersion int    `json:"world_version"`
+	}
+	if err := json.NewDecoder(file).Decode(&version); err != nil {
+		return 0, "", fmt.Errorf("%s: version.json: %w", filepath.Base(jarPath), err)
+	}
+	if version.WorldVersion == 0 {
+		return 0, "", fmt.Errorf("%s: version.json has no world_version", filepath.Base(jarPath))
+	}
+	return version.WorldVersion, version.Name, nil
+}
+
+// LevelTypes are the world presets accepted by LevelTypeProperty
+var LevelTypes = []string{"normal", "flat", "amplified", "large_biomes"}
+
+var legacyLevelTypes = map[string]string{
+	"normal":       "default",
+	"flat":         "flat",
+	"amplified":    "amplified",
+	"large_biomes": "largeBiomes",
+}
+
+// LevelTypeProperty returns the level-type value of server.properties for a preset, namespaced
+// since 1.19 and with the old names before.
+func LevelTypeProperty(levelType, version string) string {
+	if versionAtLeast(version, 19, 0) {
+		return "minecraft:" + levelType
+	}
+	return legacyLevelTypes[levelType]
+}
@@ -0,0 +1,151 @@
+package minecraft
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LogLine is one console line split into its log4j fields
+type LogLine struct {
+	Time    string `json:"time,omitempty"` // As printed, e.g. 12:00:00
+	Thread  string `json:"thread,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Logger  string `json:"logger,omitempty"`
+	Message string `json:"message"`
+	Raw     string `json:"raw"`
+	// Continuation lines (stack frames, "Caused by"...) belong to the previous line
+	Continuation bool `json:"continuation,omitempty"`
+}
+
+// LogEntry is a log record with its continuation lines folded in
+type LogEntry struct {
+	Time    string   `json:"time,omitempty"`
+	Thread  string   `json:"thread,omitempty"`
+	Level   string   `json:"level,omitempty"`
+	Logger  string   `json:"logger,omitempty"`
+	Message string   `json:"message"`
+	Stack   []string `json:"stack,omitempty"`
+}
+
+var (
+	ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+	// Vanilla:  [12:00:00] [Server thread/INFO]: Done (4.2s)!
+	// Fabric:   [12:00:00] [Server thread/INFO] (Minecraft) Done (4.2s)!
+	// Forge:    [12:00:00] [Server thread/INFO] [minecraft/DedicatedServer]: Done (4.2s)!
+	// Forge debug.log: [05Jan2024 12:00:00.123] [main/INFO] [cpw.mods.modlauncher.Launcher/MODLAUNCHER]: ...
+	threadLogRegex = regexp.MustCompile(`^\[([^\]]+)\] \[([^\]]*)/([A-Z]+)\](?: \[([^\]]+)\]| \(([^)]+)\))?:? ?(.*)$`)
+
+	// Paper:    [12:00:00 INFO]: [Essentials] Loading...
+	paperLogRegex = regexp.MustCompile(`^\[(\d{1,2}:\d{2}:\d{2})(?:\.\d+)? ([A-Z]+)\]:? ?(.*)$`)
+	pluginRegex   = regexp.MustCompile(`^\[([\w .-]+)\] `)
+
+	stackLineRegex     = regexp.MustCompile(`^(\s+at |\s*\.\.\. \d+ (more|common frames omitted)|Caused by: |\s*Suppressed: |\t)`)
+	exceptionLineRegex = regexp.MustCompile(`^([a-zA-Z_$][\w$]*\.)+[\w$]*(Exception|Error|Throwable)(: .*)?$`)
+)
+
+// StripANSI removes terminal color codes
+func StripANSI(s string) string {
+	return ansiRegex.ReplaceAllString(s, "")
+}
+
+// LogParser parses console lines one at a time, remembering the last record so
+// that continuation lines inherit its level and thread.
+type LogParser struct {
+	last *LogLine
+}
+
+func (p *LogParser) Parse(raw string) LogLine {
+	line := ParseLogLine(raw)
+	if line.Level == "" && p.last != nil && isContinuation(line.Message) {
+		line.Continuation = true
+		line.Time = p.last.Time
+		line.Thread = p.last.Thread
+		line.Level = p.last.Level
+		line.Logger = p.last.Logger
+		return line
+	}
+	p.last = &line
+	return line
+}
+
+func parseHeader(text string) LogLine {
+	if m := threadLogRegex.FindStringSubmatch(text); m != nil {
+		logger := m[4]
+		if logger == "" {
+			logger = m[5]
+		}
+		return LogLine{Time: m[1], Thread: m[2], Level: m[3], Logger: logger, Message: m[6]}
+	}
+	if m := paperLogRegex.FindStringSubmatch(text); m != nil {
+		line := LogLine{Time: m[1], Level: m[2], Message: m[3]}
+		// Plugins prefix their messages with their name
+		if pm := pluginRegex.FindStringSubmatch(line.Message); pm != nil {
+			line.Logger = pm[1]
+			line.Message = strings.TrimPrefix(line.Message, pm[0])
+		}
+		return line
+	}
+	return LogLine{Message: text}
+}
+
+func isContinuation(text string) bool {
+	return stackLineRegex.MatchString(text) || exceptionLineRegex.MatchString(text)
+}
+
+// ParseLogLine parses a line without context, continuation lines are not detected
+func ParseLogLine(raw string) LogLine {
+	line := parseHeader(StripANSI(strings.TrimRight(raw, "\r")))
+	line.Raw = raw
+	return line
+}
+
+// LogGrouper groups lines into entries as they are read, folding stack traces into the record
+// they belong to. Only the entry being read is kept.
+type LogGrouper struct {
+	parser  LogParser
+	current *LogEntry
+}
+
+// Add feeds a line and returns the previous entry once the line starts a new one
+func (g *LogGrouper) Add(raw string) (LogEntry, bool) {
+	line := g.parser.Parse(raw)
+	if line.Continuation && g.current != nil {
+		g.current.Stack = append(g.current.Stack, line.Message)
+		return LogEntry{}, false
+	}
+	previous, ok := g.Flush()
+	g.current = &LogEntry{
+		Time:    line.Time,
+		Thread:  line.Thread,
+		Level:   line.Level,
+		Logger:  line.Logger,
+		Message: line.Message,
+	}
+	return previous, ok
+}
+
+// Flush returns the entry being read, at the end of the input
+func (g *LogGrouper) Flush() (LogEntry, bool) {
+	if g.current == nil {
+		return LogEntry{}, false
+	}
+	entry := *g.current
+	g.current = nil
+	return entry, true
+}
+
+// ParseLog groups lines into entries, folding stack traces into the record they belong to
+func ParseLog(lines []string) []LogEntry {
+	entries := []LogEntry{}
+	var grouper LogGrouper
+	for _, raw := range lines {
+		if entry, ok := grouper.Add(raw); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if entry, ok := grouper.Flush(); ok {
+		entries = append(entries, entry)
+	}
+	return entries
+}
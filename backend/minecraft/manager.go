@@ -1,6 +1,7 @@
 package minecraft
 
 import (
+	"context"
 	"sync"
 )
 
@@ -32,12 +33,33 @@ func (m *Manager) GetInstance(id string) (*Instance, bool) {
 	return inst, exists
 }
 
-func (m *Manager) RemoveInstance(id string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// Instances returns a snapshot of every registered instance
+func (m *Manager) Instances() []*Instance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	if inst, exists := m.instances[id]; exists {
-		inst.Stop()
-		delete(m.instances, id)
+	list := make([]*Instance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		list = append(list, inst)
 	}
+	return list
+}
+
+// RemoveInstance stops a server and forgets it. It stays registered if it could not be stopped.
+func (m *Manager) RemoveInstance(id string) error {
+	inst, exists := m.GetInstance(id)
+	if !exists {
+		return nil
+	}
+
+	// Waiting outside the lock so other instances stay reachable meanwhile
+	if err := inst.StopAndWait(context.Background(), 0); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	delete(m.instances, id)
+	m.mu.Unlock()
+	inst.Journal().Close()
+	return nil
 }
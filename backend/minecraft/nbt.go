@@ -0,0 +1,336 @@
+package minecraft
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// NBT tag types
+const (
+	tagEnd byte = iota
+	tagByte
+	tagShort
+	tagInt
+	tagLong
+	tagFloat
+	tagDouble
+	tagByteArray
+	tagString
+	tagList
+	tagCompound
+	tagIntArray
+	tagLongArray
+)
+
+const (
+	nbtMaxDepth = 512
+	// Corrupted lengths shouldn't make us allocate gigabytes
+	nbtMaxArray = 64 * 1024 * 1024
+)
+
+// Compound is a decoded NBT compound. Values are int8, int16, int32, int64, float32, float64,
+// []byte, string, []any, Compound, []int32 or []int64 depending on their tag.
+type Compound map[string]any
+
+// ReadNBT decodes a named root tag, gzip and zlib compression are detected
+func ReadNBT(r io.Reader) (string, Compound, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var src io.Reader = br
+	switch {
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer gz.Close()
+		src = bufio.NewReader(gz)
+	case magic[0] == 0x78:
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer zr.Close()
+		src = bufio.NewReader(zr)
+	}
+
+	d := &nbtDecoder{r: src}
+	tag, err := d.byte()
+	if err != nil {
+		return "", nil, err
+	}
+	if tag != tagCompound {
+		return "", nil, fmt.Errorf("nbt: root is tag %d, not a compound", tag)
+	}
+	name, err := d.string()
+	if err != nil {
+		return "", nil, err
+	}
+	value, err := d.payload(tagCompound, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, value.(Compound), nil
+}
+
+// ReadNBTFile decodes an NBT file such as level.dat or playerdata/<uuid>.dat
+func ReadNBTFile(path string) (Compound, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, root, err := ReadNBT(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return root, nil
+}
+
+type nbtDecoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func (d *nbtDecoder) read(n int) ([]byte, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:n]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return d.buf[:n], nil
+}
+
+func (d *nbtDecoder) byte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *nbtDecoder) int16() (int16, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *nbtDecoder) int32() (int32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *nbtDecoder) int64() (int64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// string reads a length-prefixed string. Java writes modified UTF-8, which only differs from
+// UTF-8 for NUL and characters outside the BMP.
+func (d *nbtDecoder) string() (string, error) {
+	n, err := d.int16()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, uint16(n))
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *nbtDecoder) length() (int, error) {
+	n, err := d.int32()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > nbtMaxArray {
+		return 0, fmt.Errorf("nbt: invalid length %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *nbtDecoder) payload(tag byte, depth int) (any, error) {
+	if depth > nbtMaxDepth {
+		return nil, fmt.Errorf("nbt: nested too deep")
+	}
+
+	switch tag {
+	case tagByte:
+		b, err := d.byte()
+		return int8(b), err
+	case tagShort:
+		return d.int16()
+	case tagInt:
+		return d.int32()
+	case tagLong:
+		return d.int64()
+	case tagFloat:
+		v, err := d.int32()
+		return math.Float32frombits(uint32(v)), err
+	case tagDouble:
+		v, err := d.int64()
+		return math.Float64frombits(uint64(v)), err
+	case tagByteArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(d.r, b)
+		return b, err
+	case tagString:
+		return d.string()
+	case tagList:
+		elem, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]any, 0, min(n, 1024))
+		for idx := 0; idx < n; idx++ {
+			v, err := d.payload(elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case tagCompound:
+		c := Compound{}
+		for {
+			child, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			if child == tagEnd {
+				return c, nil
+			}
+			name, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			if c[name], err = d.payload(child, depth+1); err != nil {
+				return nil, err
+			}
+		}
+	case tagIntArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int32, 0, min(n, 1024))
+		for idx := 0; idx < n; idx++ {
+			v, err := d.int32()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case tagLongArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int64, 0, min(n, 1024))
+		for idx := 0; idx < n; idx++ {
+			v, err := d.int64()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("nbt: unknown tag %d", tag)
+}
+
+// Typed accessors. They return the zero value when the key is missing or of another type,
+// numbers are converted between widths since the game changed some of them over versions.
+
+func (c Compound) Compound(key string) Compound {
+	v, _ := c[key].(Compound)
+	return v
+}
+
+func (c Compound) List(key string) []any {
+	v, _ := c[key].([]any)
+	return v
+}
+
+func (c Compound) String(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c Compound) Has(key string) bool {
+	_, ok := c[key]
+	return ok
+}
+
+func (c Compound) Int(key string) int64 {
+	switch v := c[key].(type) {
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func (c Compound) Float(key string) float64 {
+	switch v := c[key].(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return float64(c.Int(key))
+}
+
+func (c Compound) Bool(key string) bool {
+	return c.Int(key) != 0
+}
+
+// Floats returns a list of numbers, e.g. Pos or Rotation
+func (c Compound) Floats(key string) []float64 {
+	list := c.List(key)
+	values := make([]float64, 0, len(list))
+	for _, v := range list {
+		values = append(values, Compound{"v": v}.Float("v"))
+	}
+	return values
+}
@@ -0,0 +1,214 @@
+package minecraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ItemStack is an item of an inventory. Tag holds the item NBT before 1.20.5 and Components after.
+type ItemStack struct {
+	Slot       int      `json:"slot"`
+	ID         string   `json:"id"`
+	Count      int      `json:"count"`
+	Tag        Compound `json:"tag,omitempty"`
+	Components Compound `json:"components,omitempty"`
+}
+
+// PlayerData is what a world saved about a player, as of the last autosave or disconnect
+type PlayerData struct {
+	UUID       string      `json:"uuid"`
+	Position   [3]float64  `json:"position"`
+	Rotation   [2]float64  `json:"rotation"`
+	Dimension  string      `json:"dimension"`
+	Health     float64     `json:"health"`
+	Food       int         `json:"food"`
+	Saturation float64     `json:"saturation"`
+	XPLevel    int         `json:"xp_level"`
+	XPTotal    int         `json:"xp_total"`
+	XPProgress float64     `json:"xp_progress"` // 0-1 towards the next level
+	GameMode   int         `json:"game_mode"`
+	Inventory  []ItemStack `json:"inventory"`
+	EnderChest []ItemStack `json:"ender_chest"`
+	ModTime    int64       `json:"mod_time"`
+}
+
+// Before 1.16 the dimension was a number
+var legacyDimensions = map[int64]string{
+	-1: "minecraft:the_nether",
+	0:  "minecraft:overworld",
+	1:  "minecraft:the_end",
+}
+
+// Since 1.21.5 armor and offhand are in "equipment" instead of the inventory, we put them back
+// at their old slot numbers so both versions look the same.
+var equipmentSlots = map[string]int{
+	"feet":    100,
+	"legs":    101,
+	"chest":   102,
+	"head":    103,
+	"offhand": -106,
+	"body":    105,
+}
+
+// ReadPlayerData reads <world>/playerdata/<uuid>.dat
+func ReadPlayerData(worldDir, uuid string) (*PlayerData, error) {
+	path := filepath.Join(worldDir, "playerdata", uuid+".dat")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := ReadNBTFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &PlayerData{
+		UUID:       uuid,
+		Health:     root.Float("Health"),
+		Food:       int(root.Int("foodLevel")),
+		Saturation: root.Float("foodSaturationLevel"),
+		XPLevel:    int(root.Int("XpLevel")),
+		XPTotal:    int(root.Int("XpTotal")),
+		XPProgress: root.Float("XpP"),
+		GameMode:   int(root.Int("playerGameType")),
+		Inventory:  parseItems(root.List("Inventory")),
+		EnderChest: parseItems(root.List("EnderItems")),
+		ModTime:    info.ModTime().Unix(),
+	}
+	copy(data.Position[:], root.Floats("Pos"))
+	copy(data.Rotation[:], root.Floats("Rotation"))
+
+	if dim, ok := root["Dimension"].(string); ok {
+		data.Dimension = dim
+	} else {
+		data.Dimension = legacyDimensions[root.Int("Dimension")]
+	}
+
+	for key, value := range root.Compound("equipment") {
+		slot, known := equipmentSlots[key]
+		item, ok := value.(Compound)
+		if !known || !ok {
+			continue
+		}
+		if stack, ok := parseItem(item); ok {
+			stack.Slot = slot
+			data.Inventory = append(data.Inventory, stack)
+		}
+	}
+	sort.SliceStable(data.Inventory, func(a, b int) bool { return data.Inventory[a].Slot < data.Inventory[b].Slot })
+
+	return data, nil
+}
+
+func parseItems(list []any) []ItemStack {
+	items := []ItemStack{}
+	for _, v := range list {
+		item, ok := v.(Compound)
+		if !ok {
+			continue
+		}
+		if stack, ok := parseItem(item); ok {
+			stack.Slot = int(item.Int("Slot"))
+			items = append(items, stack)
+		}
+	}
+	return items
+}
+
+// parseItem handles both the old (Count byte, tag) and the 1.20.5+ (count int, components) formats
+func parseItem(item Compound) (ItemStack, bool) {
+	stack := ItemStack{
+		ID:         item.String("id"),
+		Tag:        item.Compound("tag"),
+		Components: item.Compound("components"),
+	}
+	switch {
+	case item.Has("count"):
+		stack.Count = int(item.Int("count"))
+	case item.Has("Count"):
+		stack.Count = int(item.Int("Count"))
+	default:
+		stack.Count = 1
+	}
+	if stack.ID == "" || stack.ID == "minecraft:air" || stack.Count <= 0 {
+		return stack, false
+	}
+	return stack, true
+}
+
+// PlayerStats are the statistics of stats/<uuid>.json grouped by category, e.g.
+// stats["minecraft:custom"]["minecraft:play_time"]. Files from before 1.13 are flat and end up
+// in a single "legacy" category.
+type PlayerStats struct {
+	DataVersion int                         `json:"data_version,omitempty"`
+	Stats       map[string]map[string]int64 `json:"stats"`
+}
+
+// ReadPlayerStats reads <world>/stats/<uuid>.json
+func ReadPlayerStats(worldDir, uuid string) (*PlayerStats, error) {
+	raw, err := os.ReadFile(filepath.Join(worldDir, "stats", uuid+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		DataVersion int                         `json:"DataVersion"`
+		Stats       map[string]map[string]int64 `json:"stats"`
+	}
+	if err := json.Unmarshal(raw, &file); err == nil && file.Stats != nil {
+		return &PlayerStats{DataVersion: file.DataVersion, Stats: file.Stats}, nil
+	}
+
+	legacy := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse stats of %s: %w", uuid, err)
+	}
+	stats := &PlayerStats{Stats: map[string]map[string]int64{"legacy": {}}}
+	for key, value := range legacy {
+		// Achievements with progress are objects, only the counters are kept
+		var n int64
+		if json.Unmarshal(value, &n) == nil {
+			stats.Stats["legacy"][key] = n
+		}
+	}
+	return stats, nil
+}
+
+// Advancement is an entry of advancements/<uuid>.json, Criteria maps each met criterion to its date
+type Advancement struct {
+	ID       string            `json:"id"`
+	Done     bool              `json:"done"`
+	Criteria map[string]string `json:"criteria"`
+}
+
+// ReadPlayerAdvancements reads <world>/advancements/<uuid>.json, recipe unlocks are only
+// included with recipes since they make most of the file.
+func ReadPlayerAdvancements(worldDir, uuid string, recipes bool) ([]Advancement, error) {
+	raw, err := os.ReadFile(filepath.Join(worldDir, "advancements", uuid+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	file := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse advancements of %s: %w", uuid, err)
+	}
+
+	advancements := []Advancement{}
+	for id, value := range file {
+		if id == "DataVersion" || (!recipes && strings.Contains(id, ":recipes/")) {
+			continue
+		}
+		adv := Advancement{ID: id}
+		if err := json.Unmarshal(value, &adv); err != nil {
+			continue
+		}
+		advancements = append(advancements, adv)
+	}
+	sort.Slice(advancements, func(a, b int) bool { return advancements[a].ID < advancements[b].ID })
+	return advancements, nil
+}
@@ -6,10 +6,16 @@ type PlayerCacheEntry struct {
 	ExpiresOn string `json:"expiresOn"`
 }
 
+type WhitelistEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
 type OpEntry struct {
-	UUID  string `json:"uuid"`
-	Name  string `json:"name"`
-	Level int    `json:"level"`
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               int    `json:"level"`
+	BypassesPlayerLimit bool   `json:"bypassesPlayerLimit"`
 }
 
 type BanEntry struct {
@@ -20,3 +26,14 @@ type BanEntry struct {
 	Expires string `json:"expires"`
 	Reason  string `json:"reason"`
 }
+
+type IPBanEntry struct {
+	IP      string `json:"ip"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// BanTimeFormat is the date format of banned-players.json and banned-ips.json
+const BanTimeFormat = "2006-01-02 15:04:05 -0700"
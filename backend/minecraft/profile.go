@@ -0,0 +1,25 @@
+package minecraft
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+)
+
+// OfflineUUID is the UUID a server in offline mode gives a player: a version 3 UUID built
+// from the MD5 of "OfflinePlayer:<name>", like Java's UUID.nameUUIDFromBytes.
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30 // Version 3
+	sum[8] = sum[8]&0x3f | 0x80 // IETF variant
+	return FormatUUID(fmt.Sprintf("%x", sum[:]))
+}
+
+// FormatUUID adds the dashes to a 32 hex digit UUID, as Mojang's API returns them without
+func FormatUUID(id string) string {
+	id = strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if len(id) != 32 {
+		return id
+	}
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
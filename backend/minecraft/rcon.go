@@ -0,0 +1,281 @@
+package minecraft
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// Source RCON protocol as implemented by the vanilla server:
+// https://minecraft.wiki/w/RCON
+const (
+	rconTypeResponse = 0
+	rconTypeCommand  = 2
+	rconTypeAuth     = 3
+
+	rconMaxBody     = 4096 // Larger responses are split over several packets
+	rconDialTimeout = 5 * time.Second
+	rconIOTimeout   = 10 * time.Second
+
+	DefaultRCONPort = 25575
+)
+
+// ErrRCONUnavailable means RCON is not configured or not reachable, stdin is the only way in.
+var ErrRCONUnavailable = errors.New("rcon unavailable")
+
+type RCONClient struct {
+	conn   net.Conn
+	mu     sync.Mutex
+	nextID int32
+}
+
+// DialRCON connects and authenticates to an RCON server
+func DialRCON(addr, password string) (*RCONClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, rconDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &RCONClient{conn: conn, nextID: 1}
+	if err := c.auth(password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *RCONClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *RCONClient) auth(password string) error {
+	c.conn.SetDeadline(time.Now().Add(rconIOTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	id := c.newID()
+	if err := c.writePacket(id, rconTypeAuth, password); err != nil {
+		return err
+	}
+	for {
+		respID, respType, _, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		// The server may send an empty RESPONSE_VALUE before the auth response
+		if respType != rconTypeCommand {
+			continue
+		}
+		if respID == -1 {
+			return fmt.Errorf("rcon authentication failed")
+		}
+		return nil
+	}
+}
+
+// Command runs a command and returns its output. Commands on one client are serialized.
+func (c *RCONClient) Command(cmd string) (string, error) {
+	if len(cmd) > 1446 {
+		return "", fmt.Errorf("command too long for rcon")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn.SetDeadline(time.Now().Add(rconIOTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	id := c.newID()
+	if err := c.writePacket(id, rconTypeCommand, cmd); err != nil {
+		return "", err
+	}
+	// The server answers packets in order: once the reply to this dummy packet shows up,
+	// every fragment of the command response has been received.
+	endID := c.newID()
+	if err := c.writePacket(endID, rconTypeResponse, ""); err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	for {
+		respID, _, body, err := c.readPacket()
+		if err != nil {
+			return "", err
+		}
+		switch respID {
+		case id:
+			out.WriteString(body)
+		case endID:
+			return out.String(), nil
+		case -1:
+			return "", fmt.Errorf("rcon session is not authenticated")
+		}
+	}
+}
+
+func (c *RCONClient) newID() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+	return id
+}
+
+func (c *RCONClient) writePacket(id, pType int32, body string) error {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(len(body)+10))
+	binary.Write(buf, binary.LittleEndian, id)
+	binary.Write(buf, binary.LittleEndian, pType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+	_, err := c.conn.Write(buf.Bytes())
+	return err
+}
+
+func (c *RCONClient) readPacket() (id, pType int32, body string, err error) {
+	var size int32
+	if err = binary.Read(c.conn, binary.LittleEndian, &size); err != nil {
+		return
+	}
+	if size < 10 || size > rconMaxBody+10 {
+		err = fmt.Errorf("invalid rcon packet size %d", size)
+		return
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(c.conn, data); err != nil {
+		return
+	}
+	id = int32(binary.LittleEndian.Uint32(data[0:4]))
+	pType = int32(binary.LittleEndian.Uint32(data[4:8]))
+	body = string(bytes.TrimRight(data[8:], "\x00"))
+	return
+}
+
+// EnsureRCONProperties turns RCON on in a properties map, generating a password when none is set.
+// It returns true if the map was changed and needs saving.
+func EnsureRCONProperties(props map[string]string, port int) bool {
+	changed := false
+	if props["enable-rcon"] != "true" {
+		props["enable-rcon"] = "true"
+		changed = true
+	}
+	if props["rcon.password"] == "" {
+		secret := make([]byte, 16)
+		rand.Read(secret)
+		props["rcon.password"] = hex.EncodeToString(secret)
+		changed = true
+	}
+	if _, err := strconv.Atoi(props["rcon.port"]); err != nil {
+		props["rcon.port"] = strconv.Itoa(port)
+		changed = true
+	}
+	// The panel reads the output itself, no need to echo every command to ops
+	if props["broadcast-rcon-to-ops"] == "" {
+		props["broadcast-rcon-to-ops"] = "false"
+		changed = true
+	}
+	return changed
+}
+
+// SetRCON sets where the instance reaches its RCON server. An empty password disables RCON.
+func (i *Instance) SetRCON(port int, password string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	addr := ""
+	if password != "" && port > 0 {
+		addr = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	}
+	if addr != i.rconAddr || password != i.rconPassword {
+		i.closeRCONLocked()
+	}
+	i.rconAddr = addr
+	i.rconPassword = password
+}
+
+// RCON runs a command through RCON and returns its output. The connection is kept
+// open and reused for the following commands until the process exits.
+func (i *Instance) RCON(cmd string) (string, error) {
+	switch i.GetStatus() {
+	case core.StatusRunning:
+	case core.StatusStarting:
+		return "", fmt.Errorf("server is still starting")
+	default:
+		return "", fmt.Errorf("server stopped")
+	}
+
+	client, err := i.rconClient()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.Command(cmd)
+	if err == nil {
+		return out, nil
+	}
+
+	// Stale connection (server reloaded, timeout...), retry once on a fresh one
+	i.dropRCON(client)
+	client, err = i.rconClient()
+	if err != nil {
+		return "", err
+	}
+	out, err = client.Command(cmd)
+	if err != nil {
+		i.dropRCON(client)
+		return "", err
+	}
+	return out, nil
+}
+
+func (i *Instance) rconClient() (*RCONClient, error) {
+	i.rconMu.Lock()
+	defer i.rconMu.Unlock()
+
+	i.mu.RLock()
+	client, addr, password := i.rcon, i.rconAddr, i.rconPassword
+	i.mu.RUnlock()
+
+	if client != nil {
+		return client, nil
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("%w: not enabled for this server", ErrRCONUnavailable)
+	}
+
+	client, err := DialRCON(addr, password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRCONUnavailable, err)
+	}
+
+	i.mu.Lock()
+	i.rcon = client
+	i.mu.Unlock()
+	return client, nil
+}
+
+func (i *Instance) dropRCON(client *RCONClient) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.rcon == client {
+		i.closeRCONLocked()
+	}
+}
+
+// closeRCONLocked closes the pooled connection. Caller must hold i.mu.
+func (i *Instance) closeRCONLocked() {
+	if i.rcon != nil {
+		i.rcon.Close()
+		i.rcon = nil
+	}
+}
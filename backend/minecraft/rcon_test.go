@@ -0,0 +1,176 @@
+package minecraft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// fakeRCON is an in-process RCON server behaving like the vanilla one: an empty
+// RESPONSE_VALUE before the auth answer, long outputs split over 4096 byte packets.
+type fakeRCON struct {
+	ln       net.Listener
+	password string
+	conns    atomic.Int32
+	handle   func(cmd string) string
+}
+
+func newFakeRCON(t *testing.T, password string, handle func(string) string) *fakeRCON {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRCON{ln: ln, password: password, handle: handle}
+	t.Cleanup(func() { ln.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeRCON) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeRCON) port() int { return f.ln.Addr().(*net.TCPAddr).Port }
+
+func (f *fakeRCON) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.conns.Add(1)
+		go f.session(conn)
+	}
+}
+
+func (f *fakeRCON) session(conn net.Conn) {
+	defer conn.Close()
+	authed := false
+	for {
+		var size int32
+		if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
+			return
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		id := int32(binary.LittleEndian.Uint32(data[0:4]))
+		pType := int32(binary.LittleEndian.Uint32(data[4:8]))
+		body := string(bytes.TrimRight(data[8:], "\x00"))
+
+		switch {
+		case pType == rconTypeAuth:
+			writeFakePacket(conn, id, rconTypeResponse, "")
+			if body != f.password {
+				writeFakePacket(conn, -1, rconTypeCommand, "")
+				continue
+			}
+			authed = true
+			writeFakePacket(conn, id, rconTypeCommand, "")
+		case !authed:
+			writeFakePacket(conn, -1, rconTypeResponse, "")
+		case pType == rconTypeCommand:
+			out := f.handle(body)
+			for len(out) > rconMaxBody {
+				writeFakePacket(conn, id, rconTypeResponse, out[:rconMaxBody])
+				out = out[rconMaxBody:]
+			}
+			writeFakePacket(conn, id, rconTypeResponse, out)
+		default:
+			// Unknown packet types get an "Unknown request" answer, used as end marker
+			writeFakePacket(conn, id, rconTypeResponse, "Unknown request 0")
+		}
+	}
+}
+
+func writeFakePacket(w io.Writer, id, pType int32, body string) {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, int32(len(body)+10))
+	binary.Write(buf, binary.LittleEndian, id)
+	binary.Write(buf, binary.LittleEndian, pType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+	w.Write(buf.Bytes())
+}
+
+func TestRCONCommand(t *testing.T) {
+	fake := newFakeRCON(t, "secret", func(cmd string) string { return "ran " + cmd })
+
+	client, err := DialRCON(fake.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, cmd := range []string{"list", "whitelist add Steve"} {
+		out, err := client.Command(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != "ran "+cmd {
+			t.Errorf("Command(%q) = %q", cmd, out)
+		}
+	}
+}
+
+func TestRCONFragmentedResponse(t *testing.T) {
+	long := strings.Repeat("a", rconMaxBody) + strings.Repeat("b", rconMaxBody) + "end"
+	fake := newFakeRCON(t, "secret", func(string) string { return long })
+
+	client, err := DialRCON(fake.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	out, err := client.Command("help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != long {
+		t.Errorf("got %d bytes, want %d", len(out), len(long))
+	}
+}
+
+func TestRCONBadPassword(t *testing.T) {
+	fake := newFakeRCON(t, "secret", func(string) string { return "" })
+
+	if _, err := DialRCON(fake.addr(), "wrong"); err == nil {
+		t.Fatal("expected an authentication error")
+	}
+}
+
+func TestInstanceRCONPool(t *testing.T) {
+	fake := newFakeRCON(t, "secret", func(cmd string) string { return cmd })
+
+	inst := NewInstance("test", t.TempDir(), "server.jar")
+	if _, err := inst.RCON("list"); err == nil {
+		t.Fatal("expected an error while the server is stopped")
+	}
+
+	inst.SetStatus(core.StatusRunning)
+	if _, err := inst.RCON("list"); !errors.Is(err, ErrRCONUnavailable) {
+		t.Fatalf("expected ErrRCONUnavailable without settings, got %v", err)
+	}
+
+	inst.SetRCON(fake.port(), "secret")
+	for idx := 0; idx < 3; idx++ {
+		out, err := inst.RCON("list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != "list" {
+			t.Errorf("RCON(list) = %q", out)
+		}
+	}
+	if n := fake.conns.Load(); n != 1 {
+		t.Errorf("%d connections opened, the pooled one should be reused", n)
+	}
+}
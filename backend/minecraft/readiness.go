@@ -0,0 +1,84 @@
+package minecraft
+
+import (
+	"fmt"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// Vanilla, Paper, Fabric and Forge all print this line once the world is loaded:
+// [12:00:00] [Server thread/INFO]: Done (4.213s)! For help, type "help"
+var defaultReadyRegex = regexp.MustCompile(`Done \(([\d.,]+)s\)! For help`)
+
+const DefaultStartupTimeout = 5 * time.Minute
+
+// SetReadiness configures how the instance decides it is ready. An empty pattern
+// restores the default "Done" line detection, a zero timeout the default timeout.
+func (i *Instance) SetReadiness(pattern string, timeout time.Duration) error {
+	re := defaultReadyRegex
+	if pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid ready pattern: %w", err)
+		}
+		re = compiled
+	}
+	if timeout <= 0 {
+		timeout = DefaultStartupTimeout
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.ReadyPattern = re
+	i.StartupTimeout = timeout
+	return nil
+}
+
+// GetBootTime returns how long the last start took to reach RUNNING (0 if it never did).
+func (i *Instance) GetBootTime() time.Duration {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.bootTime
+}
+
+// checkReady switches the instance to RUNNING when a STARTING server prints its ready line.
+func (i *Instance) checkReady(line string) bool {
+	i.mu.Lock()
+	if i.status != core.StatusStarting || i.ReadyPattern == nil || !i.ReadyPattern.MatchString(line) {
+		i.mu.Unlock()
+		return false
+	}
+	if i.startupTimer != nil {
+		i.startupTimer.Stop()
+		i.startupTimer = nil
+	}
+	i.bootTime = time.Since(i.startedAt)
+	bootTime := i.bootTime
+	replaying := i.replaying
+	i.mu.Unlock()
+
+	i.SetStatus(core.StatusRunning)
+	if !replaying {
+		i.broadcastLog(fmt.Sprintf("--- SERVER READY in %.1fs ---", bootTime.Seconds()))
+	}
+	return true
+}
+
+// startupTimedOut kills a server that did not become ready in time, which is then handled as a crash.
+func (i *Instance) startupTimedOut() {
+	i.mu.Lock()
+	i.startupTimer = nil
+	if i.status != core.StatusStarting || i.conn == nil {
+		i.mu.Unlock()
+		return
+	}
+	timeout := i.StartupTimeout
+	i.startFailure = fmt.Sprintf("startup timeout: not ready after %s", timeout)
+	i.mu.Unlock()
+
+	i.broadcastLog(fmt.Sprintf("--- STARTUP FAILED: not ready after %s, killing process ---", timeout))
+	i.signal(syscall.SIGKILL)
+}
@@ -0,0 +1,194 @@
+package minecraft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// RestartConfig describes what happens when the process exits without being asked to.
+type RestartConfig struct {
+	Policy      core.RestartPolicy
+	MaxRetries  int           // Consecutive restart attempts before giving up (0 = unlimited)
+	BaseBackoff time.Duration // Delay before the first attempt, doubled on each retry
+	MaxBackoff  time.Duration
+
+	// Crash-loop breaker: too many crashes within LoopWindow disables restarts
+	LoopWindow    time.Duration
+	LoopThreshold int
+
+	// A run lasting longer than StableAfter resets the retry counter
+	StableAfter time.Duration
+}
+
+func DefaultRestartConfig() RestartConfig {
+	return RestartConfig{
+		Policy:        core.RestartNever,
+		MaxRetries:    3,
+		BaseBackoff:   10 * time.Second,
+		MaxBackoff:    5 * time.Minute,
+		LoopWindow:    10 * time.Minute,
+		LoopThreshold: 5,
+		StableAfter:   5 * time.Minute,
+	}
+}
+
+// CrashEvent is broadcast (type "crash") every time the process dies unexpectedly.
+type CrashEvent struct {
+	Time       time.Time `json:"time"`
+	ExitCode   int       `json:"exit_code"`
+	Reason     string    `json:"reason"`
+	Uptime     float64   `json:"uptime"` // Seconds
+	Restarting bool      `json:"restarting"`
+	RestartIn  float64   `json:"restart_in,omitempty"` // Seconds
+	Attempt    int       `json:"attempt,omitempty"`
+	GaveUp     string    `json:"gave_up,omitempty"` // Why no restart was scheduled
+
+	Analysis *CrashAnalysis `json:"analysis,omitempty"` // Crash reports of the run and known causes
+}
+
+// restartState tracks the automatic restarts of an instance, protected by Instance.mu.
+type restartState struct {
+	retries int
+	total   int
+	crashes []time.Time
+	timer   *time.Timer
+}
+
+func (i *Instance) SetRestartPolicy(policy core.RestartPolicy, maxRetries int, backoff time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !policy.IsValid() {
+		policy = core.RestartNever
+	}
+	i.Restart.Policy = policy
+	i.Restart.MaxRetries = maxRetries
+	if backoff > 0 {
+		i.Restart.BaseBackoff = backoff
+	}
+}
+
+// GetRestartCount returns how many automatic restarts happened since the panel started.
+func (i *Instance) GetRestartCount() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.restart.total
+}
+
+// cancelPendingRestart stops a scheduled automatic restart. Caller must hold i.mu.
+func (i *Instance) cancelPendingRestart() {
+	if i.restart.timer != nil {
+		i.restart.timer.Stop()
+		i.restart.timer = nil
+	}
+}
+
+// handleExit decides, once the process is gone, whether it crashed and whether to bring it back.
+func (i *Instance) handleExit(exitCode int, stopRequested bool, uptime time.Duration, reason string) {
+	crashed := !stopRequested && exitCode != 0
+
+	i.mu.Lock()
+	cfg := i.Restart
+
+	if uptime >= cfg.StableAfter {
+		i.restart.retries = 0
+	}
+
+	shouldRestart := false
+	switch cfg.Policy {
+	case core.RestartAlways:
+		shouldRestart = !stopRequested
+	case core.RestartOnFailure:
+		shouldRestart = crashed
+	}
+
+	now := time.Now()
+	gaveUp := ""
+	if crashed {
+		// Keep only the crashes inside the breaker window
+		recent := i.restart.crashes[:0]
+		for _, t := range i.restart.crashes {
+			if now.Sub(t) < cfg.LoopWindow {
+				recent = append(recent, t)
+			}
+		}
+		i.restart.crashes = append(recent, now)
+
+		if shouldRestart && cfg.LoopThreshold > 0 && len(i.restart.crashes) >= cfg.LoopThreshold {
+			shouldRestart = false
+			gaveUp = fmt.Sprintf("crash loop detected (%d crashes in %s)", len(i.restart.crashes), cfg.LoopWindow)
+		}
+	}
+	if shouldRestart && cfg.MaxRetries > 0 && i.restart.retries >= cfg.MaxRetries {
+		shouldRestart = false
+		gaveUp = fmt.Sprintf("max retries reached (%d)", cfg.MaxRetries)
+	}
+	if stopRequested {
+		i.restart.retries = 0
+	}
+
+	var delay time.Duration
+	if shouldRestart {
+		i.restart.retries++
+		delay = backoffDelay(cfg, i.restart.retries)
+		i.cancelPendingRestart()
+		i.restart.timer = time.AfterFunc(delay, i.autoRestart)
+	}
+	attempt := i.restart.retries
+	i.mu.Unlock()
+
+	if crashed {
+		i.SetStatus(core.StatusCrashed)
+	} else {
+		i.SetStatus(core.StatusStopped)
+	}
+
+	if crashed || shouldRestart {
+		event := CrashEvent{
+			Time:       now,
+			ExitCode:   exitCode,
+			Reason:     reason,
+			Uptime:     uptime.Seconds(),
+			Restarting: shouldRestart,
+			GaveUp:     gaveUp,
+		}
+		if crashed {
+			event.Analysis = i.analyzeCrash(now.Add(-uptime))
+			for _, finding := range event.Analysis.Findings {
+				i.broadcastLog(fmt.Sprintf("--- CRASH CAUSE: %s (%s) ---", finding.Title, finding.Suggestion))
+			}
+		}
+		if shouldRestart {
+			event.RestartIn = delay.Seconds()
+			event.Attempt = attempt
+			i.broadcastLog(fmt.Sprintf("--- RESTARTING IN %s (attempt %d) ---", delay, attempt))
+		} else if gaveUp != "" {
+			i.broadcastLog(fmt.Sprintf("--- AUTO-RESTART DISABLED: %s ---", gaveUp))
+		}
+		i.broadcast(WSMessage{Type: "crash", Data: event})
+	}
+}
+
+func (i *Instance) autoRestart() {
+	i.mu.Lock()
+	i.restart.timer = nil
+	i.restart.total++
+	i.mu.Unlock()
+
+	if err := i.Start(); err != nil {
+		i.broadcastLog(fmt.Sprintf("--- AUTO-RESTART FAILED: %v ---", err))
+	}
+}
+
+func backoffDelay(cfg RestartConfig, attempt int) time.Duration {
+	delay := cfg.BaseBackoff
+	for n := 1; n < attempt; n++ {
+		delay *= 2
+		if cfg.MaxBackoff > 0 && delay >= cfg.MaxBackoff {
+			return cfg.MaxBackoff
+		}
+	}
+	return delay
+}
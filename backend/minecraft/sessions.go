@@ -0,0 +1,161 @@
+package minecraft
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	PlayerJoin  = "join"
+	PlayerLeave = "leave"
+)
+
+// PlayerEvent is a join or leave read from the console
+type PlayerEvent struct {
+	Type   string
+	Name   string
+	UUID   string
+	IP     string
+	Reason string // Disconnect reason, leave only
+	Time   time.Time
+}
+
+var (
+	// UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5
+	playerUUIDRegex = regexp.MustCompile(`UUID of player (\w+) is ([0-9a-fA-F-]{32,36})`)
+	// Steve[/127.0.0.1:54321] logged in with entity id 123 at (0.5, 64.0, 0.5)
+	playerLoginRegex = regexp.MustCompile(`]: (\w+)\[/?(.+?)\] logged in with entity id`)
+	// Steve lost connection: Disconnected
+	playerLostRegex = regexp.MustCompile(`]: (\w+) lost connection: (.*)$`)
+)
+
+// pendingPlayer gathers what the server prints about a player around its join and leave lines
+type pendingPlayer struct {
+	uuid, ip, reason string
+}
+
+// trackPlayer updates the connected players from a console line. Events are only reported
+// for live output, not while replaying a log.
+func (i *Instance) trackPlayer(text string) {
+	var event *PlayerEvent
+
+	i.playersMu.Lock()
+	pending := func(name string) *pendingPlayer {
+		p, ok := i.pendingPlayers[name]
+		if !ok {
+			p = &pendingPlayer{}
+			i.pendingPlayers[name] = p
+		}
+		return p
+	}
+	switch {
+	case playerUUIDRegex.MatchString(text):
+		m := playerUUIDRegex.FindStringSubmatch(text)
+		pending(m[1]).uuid = m[2]
+	case playerLoginRegex.MatchString(text):
+		m := playerLoginRegex.FindStringSubmatch(text)
+		ip := m[2]
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		pending(m[1]).ip = ip
+	case playerLostRegex.MatchString(text):
+		m := playerLostRegex.FindStringSubmatch(text)
+		pending(m[1]).reason = strings.TrimSpace(m[2])
+	case joinRegex.MatchString(text):
+		name := joinRegex.FindStringSubmatch(text)[1]
+		i.ConnectedPlayers[name] = true
+		p := pending(name)
+		event = &PlayerEvent{Type: PlayerJoin, Name: name, UUID: p.uuid, IP: p.ip}
+		p.reason = ""
+	case leaveRegex.MatchString(text):
+		name := leaveRegex.FindStringSubmatch(text)[1]
+		delete(i.ConnectedPlayers, name)
+		p := pending(name)
+		event = &PlayerEvent{Type: PlayerLeave, Name: name, UUID: p.uuid, IP: p.ip, Reason: p.reason}
+		delete(i.pendingPlayers, name)
+	}
+	i.playersMu.Unlock()
+
+	if event != nil {
+		event.Time = time.Now()
+		i.emitPlayerEvent(*event)
+	}
+}
+
+// clearPlayers forgets every connected player, reporting them as gone with reason
+func (i *Instance) clearPlayers(reason string) {
+	i.playersMu.Lock()
+	names := make([]string, 0, len(i.ConnectedPlayers))
+	for name := range i.ConnectedPlayers {
+		names = append(names, name)
+	}
+	i.ConnectedPlayers = make(map[string]bool)
+	i.pendingPlayers = make(map[string]*pendingPlayer)
+	i.playersMu.Unlock()
+
+	now := time.Now()
+	for _, name := range names {
+		i.emitPlayerEvent(PlayerEvent{Type: PlayerLeave, Name: name, Reason: reason, Time: now})
+	}
+}
+
+// reportPlayerChanges emits a join or leave for every difference between before and the
+// players connected now, for changes that were not seen in the live output
+func (i *Instance) reportPlayerChanges(before []string) {
+	var events []PlayerEvent
+	now := time.Now()
+
+	i.playersMu.Lock()
+	wasOnline := make(map[string]bool, len(before))
+	for _, name := range before {
+		wasOnline[name] = true
+		if !i.ConnectedPlayers[name] {
+			event := PlayerEvent{Type: PlayerLeave, Name: name, Time: now}
+			if p, ok := i.pendingPlayers[name]; ok {
+				event.UUID, event.IP = p.uuid, p.ip
+				delete(i.pendingPlayers, name)
+			}
+			events = append(events, event)
+		}
+	}
+	for name := range i.ConnectedPlayers {
+		if !wasOnline[name] {
+			event := PlayerEvent{Type: PlayerJoin, Name: name, Time: now}
+			if p, ok := i.pendingPlayers[name]; ok {
+				event.UUID, event.IP = p.uuid, p.ip
+			}
+			events = append(events, event)
+		}
+	}
+	i.playersMu.Unlock()
+
+	for _, event := range events {
+		i.emitPlayerEvent(event)
+	}
+}
+
+func (i *Instance) emitPlayerEvent(event PlayerEvent) {
+	i.mu.RLock()
+	replaying, hook := i.replaying, i.OnPlayerEvent
+	i.mu.RUnlock()
+	if !replaying && hook != nil {
+		hook(event)
+	}
+}
+
+// OnlinePlayers returns the names of the connected players, sorted
+func (i *Instance) OnlinePlayers() []string {
+	i.playersMu.RLock()
+	defer i.playersMu.RUnlock()
+
+	names := make([]string, 0, len(i.ConnectedPlayers))
+	for name := range i.ConnectedPlayers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
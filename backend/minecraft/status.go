@@ -0,0 +1,433 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const probeTimeout = 3 * time.Second
+
+type PlayerSample struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// StatusReport is what the server itself answers to clients, as opposed to the process state
+type StatusReport struct {
+	Online        bool           `json:"online"`
+	Latency       int64          `json:"latency_ms"`
+	MOTD          string         `json:"motd"`
+	Version       string         `json:"version"`
+	Protocol      int            `json:"protocol"`
+	PlayersOnline int            `json:"players_online"`
+	PlayersMax    int            `json:"players_max"`
+	Sample        []PlayerSample `json:"sample"`
+	Players       []string       `json:"players,omitempty"` // Full list, only from query
+	Software      string         `json:"software,omitempty"`
+	Map           string         `json:"map,omitempty"`
+	Plugins       []string       `json:"plugins"`
+	Sources       []string       `json:"sources"` // "ping" and/or "query"
+	Error         string         `json:"error,omitempty"`
+}
+
+// ---- Server List Ping (TCP) ----
+// https://minecraft.wiki/w/Java_Edition_protocol/Server_List_Ping
+
+type PingResponse struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int            `json:"max"`
+		Online int            `json:"online"`
+		Sample []PlayerSample `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+	Latency     time.Duration   `json:"-"`
+}
+
+// MOTD returns the description as plain text, whether it is a string or a chat component
+func (p *PingResponse) MOTD() string {
+	var text string
+	if err := json.Unmarshal(p.Description, &text); err == nil {
+		return stripFormatting(text)
+	}
+	var component chatComponent
+	if err := json.Unmarshal(p.Description, &component); err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	component.flatten(&sb)
+	return stripFormatting(sb.String())
+}
+
+type chatComponent struct {
+	Text  string          `json:"text"`
+	Extra []chatComponent `json:"extra"`
+}
+
+func (c *chatComponent) UnmarshalJSON(data []byte) error {
+	// Extra entries may be bare strings
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		c.Text = text
+		return nil
+	}
+	type plain chatComponent
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+func (c *chatComponent) flatten(sb *strings.Builder) {
+	sb.WriteString(c.Text)
+	for i := range c.Extra {
+		c.Extra[i].flatten(sb)
+	}
+}
+
+// stripFormatting removes legacy § color codes
+func stripFormatting(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for n := 0; n < len(runes); n++ {
+		if runes[n] == '§' {
+			n++
+			continue
+		}
+		sb.WriteRune(runes[n])
+	}
+	return sb.String()
+}
+
+// Ping runs a status handshake against a Java Edition server
+func Ping(host string, port int, timeout time.Duration) (*PingResponse, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// Handshake: protocol version -1 (any), address, port, next state 1 (status)
+	handshake := new(bytes.Buffer)
+	writeVarInt(handshake, 0x00)
+	writeVarInt(handshake, -1)
+	writeVarInt(handshake, int32(len(host)))
+	handshake.WriteString(host)
+	binary.Write(handshake, binary.BigEndian, uint16(port))
+	writeVarInt(handshake, 1)
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+
+	// Status request
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	payload, err := readPacket(reader)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewReader(payload)
+	if id, err := readVarInt(body); err != nil || id != 0x00 {
+		return nil, fmt.Errorf("unexpected status packet")
+	}
+	length, err := readVarInt(body)
+	if err != nil || length < 0 || int(length) > body.Len() {
+		return nil, fmt.Errorf("invalid status response")
+	}
+	raw := make([]byte, length)
+	io.ReadFull(body, raw)
+
+	var resp PingResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("invalid status json: %w", err)
+	}
+
+	// Ping / pong for the latency, older servers may just close the connection
+	pingPacket := new(bytes.Buffer)
+	writeVarInt(pingPacket, 0x01)
+	binary.Write(pingPacket, binary.BigEndian, time.Now().UnixMilli())
+	sent := time.Now()
+	if err := writePacket(conn, pingPacket.Bytes()); err == nil {
+		if _, err := readPacket(reader); err == nil {
+			resp.Latency = time.Since(sent)
+		}
+	}
+
+	return &resp, nil
+}
+
+func writePacket(w io.Writer, payload []byte) error {
+	buf := new(bytes.Buffer)
+	writeVarInt(buf, int32(len(payload)))
+	buf.Write(payload)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readPacket(r io.ByteReader) ([]byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > 1<<21 {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	payload := make([]byte, length)
+	for n := range payload {
+		if payload[n], err = r.ReadByte(); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+		buf.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var value uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return int32(value), nil
+		}
+	}
+	return 0, fmt.Errorf("varint too long")
+}
+
+// ---- GameSpy4 query (UDP), needs enable-query=true ----
+// https://minecraft.wiki/w/Query
+
+type QueryResponse struct {
+	MOTD          string
+	GameType      string
+	Version       string
+	Software      string
+	Plugins       []string
+	Map           string
+	PlayersOnline int
+	PlayersMax    int
+	Players       []string
+}
+
+// Query runs a full stat request
+func Query(host string, port int, timeout time.Duration) (*QueryResponse, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var sessionBytes [4]byte
+	rand.Read(sessionBytes[:])
+	session := binary.BigEndian.Uint32(sessionBytes[:]) & 0x0F0F0F0F
+
+	// Handshake, answered with the challenge token as an ASCII number
+	req := new(bytes.Buffer)
+	req.Write([]byte{0xFE, 0xFD, 0x09})
+	binary.Write(req, binary.BigEndian, session)
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n < 6 || buf[0] != 0x09 {
+		return nil, fmt.Errorf("invalid query handshake response")
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(buf[5:n], "\x00")), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge token")
+	}
+
+	// Full stat: padded with 4 bytes, otherwise the server answers with the basic stat
+	req.Reset()
+	req.Write([]byte{0xFE, 0xFD, 0x00})
+	binary.Write(req, binary.BigEndian, session)
+	binary.Write(req, binary.BigEndian, int32(token))
+	req.Write([]byte{0, 0, 0, 0})
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+	n, err = conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return parseFullStat(buf[:n])
+}
+
+func parseFullStat(data []byte) (*QueryResponse, error) {
+	// type (1) + session (4) + "splitnum\x00\x80\x00" (11)
+	if len(data) < 16 || data[0] != 0x00 {
+		return nil, fmt.Errorf("invalid query response")
+	}
+	data = data[16:]
+
+	kv := make(map[string]string)
+	for {
+		key, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok {
+			return nil, fmt.Errorf("truncated query response")
+		}
+		data = rest
+		if len(key) == 0 {
+			break
+		}
+		value, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok {
+			return nil, fmt.Errorf("truncated query response")
+		}
+		data = rest
+		kv[string(key)] = string(value)
+	}
+
+	resp := &QueryResponse{
+		MOTD:     stripFormatting(kv["hostname"]),
+		GameType: kv["gametype"],
+		Version:  kv["version"],
+		Map:      kv["map"],
+		Players:  []string{},
+		Plugins:  []string{},
+	}
+	resp.PlayersOnline, _ = strconv.Atoi(kv["numplayers"])
+	resp.PlayersMax, _ = strconv.Atoi(kv["maxplayers"])
+
+	// "Paper on 1.20.4: Essentials 2.20; WorldEdit 7.3", vanilla leaves it empty
+	if plugins := kv["plugins"]; plugins != "" {
+		software, list, found := strings.Cut(plugins, ": ")
+		resp.Software = software
+		if found {
+			for _, p := range strings.Split(list, "; ") {
+				if p = strings.TrimSpace(p); p != "" {
+					resp.Plugins = append(resp.Plugins, p)
+				}
+			}
+		}
+	}
+
+	// "\x01player_\x00\x00" then one null-terminated name per player
+	if _, rest, ok := bytes.Cut(data, []byte("\x01player_\x00\x00")); ok {
+		for _, name := range bytes.Split(rest, []byte{0}) {
+			if len(name) > 0 {
+				resp.Players = append(resp.Players, string(name))
+			}
+		}
+	}
+	return resp, nil
+}
+
+// Probe pings the server and, if queryPort > 0, queries it, merging both answers.
+// The report is Online as soon as one of them answered.
+func Probe(host string, gamePort, queryPort int) *StatusReport {
+	report := &StatusReport{
+		Sample:  []PlayerSample{},
+		Plugins: []string{},
+		Sources: []string{},
+	}
+
+	errs := []string{}
+	if ping, err := Ping(host, gamePort, probeTimeout); err != nil {
+		errs = append(errs, "ping: "+err.Error())
+	} else {
+		report.Online = true
+		report.Sources = append(report.Sources, "ping")
+		report.Latency = ping.Latency.Milliseconds()
+		report.MOTD = ping.MOTD()
+		report.Version = ping.Version.Name
+		report.Protocol = ping.Version.Protocol
+		report.PlayersOnline = ping.Players.Online
+		report.PlayersMax = ping.Players.Max
+		if ping.Players.Sample != nil {
+			report.Sample = ping.Players.Sample
+		}
+	}
+
+	if queryPort > 0 {
+		if query, err := Query(host, queryPort, probeTimeout); err != nil {
+			errs = append(errs, "query: "+err.Error())
+		} else {
+			report.Online = true
+			report.Sources = append(report.Sources, "query")
+			report.Players = query.Players
+			report.Software = query.Software
+			report.Map = query.Map
+			report.Plugins = query.Plugins
+			report.PlayersOnline = query.PlayersOnline
+			report.PlayersMax = query.PlayersMax
+			if report.MOTD == "" {
+				report.MOTD = query.MOTD
+			}
+			if report.Version == "" {
+				report.Version = query.Version
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		report.Error = strings.Join(errs, "; ")
+	}
+	return report
+}
+
+// FullPlayerList returns every connected player name if the report has an exhaustive list
+func (r *StatusReport) FullPlayerList() ([]string, bool) {
+	if r.Players != nil {
+		return r.Players, true
+	}
+	if r.Online && len(r.Sample) == r.PlayersOnline {
+		names := make([]string, 0, len(r.Sample))
+		for _, p := range r.Sample {
+			// Players hiding from server listings show up as "Anonymous Player"
+			if p.ID == "00000000-0000-0000-0000-000000000000" {
+				return nil, false
+			}
+			names = append(names, p.Name)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// SyncPlayers replaces the players tracked from the logs by an authoritative list, the
+// differences are reported as joins and leaves
+func (i *Instance) SyncPlayers(names []string) {
+	players := make(map[string]bool, len(names))
+	for _, name := range names {
+		players[name] = true
+	}
+
+	before := i.OnlinePlayers()
+	i.playersMu.Lock()
+	i.ConnectedPlayers = players
+	i.playersMu.Unlock()
+	i.reportPlayerChanges(before)
+}
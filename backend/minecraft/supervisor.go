@@ -0,0 +1,387 @@
+package minecraft
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Every server runs under a small supervisor process ("crafteur supervise") started in its
+// own session, so the JVM outlives the panel. The supervisor owns the java process and keeps,
+// in <RunDir>/.crafteur:
+//   - supervisor.sock: unix socket, lines written to it go to stdin, stdout is streamed back
+//   - console.log:     stdout of the current run, rotated every maxConsoleSegment bytes into
+//     console.log.0 (first segment, kept for the boot output) then console.log.1
+//   - state.json:      pids and start time, present while the supervisor is alive
+//   - exit.json:       exit status of the last run
+//
+// With systemd, the panel unit needs KillMode=process or the supervisors are killed with it.
+const (
+	SupervisorDir      = ".crafteur"
+	supervisorSocket   = "supervisor.sock"
+	supervisorLog      = "console.log"
+	supervisorState    = "state.json"
+	supervisorExit     = "exit.json"
+	supervisorOutput   = "supervisor.log"
+	firstClientTimeout = 30 * time.Second
+	maxConsoleSegment  = 16 << 20
+	clientDrainTimeout = 10 * time.Second
+)
+
+type SupervisorState struct {
+	SupervisorPID int       `json:"supervisor_pid"`
+	JavaPID       int       `json:"java_pid"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+type SupervisorExit struct {
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+func supervisorPath(runDir, name string) string {
+	return filepath.Join(runDir, SupervisorDir, name)
+}
+
+// consoleLogFiles returns the console.log segments of the current run, oldest first
+func consoleLogFiles(runDir string) []string {
+	var files []string
+	for _, name := range []string{supervisorLog + ".0", supervisorLog + ".1", supervisorLog} {
+		if path := supervisorPath(runDir, name); isRegularFile(path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// RunSupervisor is the entry point of "crafteur supervise -- <java> <args...>", run from the server directory.
+func RunSupervisor(args []string) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: crafteur supervise -- <java> <args...>")
+	}
+
+	if err := os.MkdirAll(SupervisorDir, 0755); err != nil {
+		return err
+	}
+	sockPath := filepath.Join(SupervisorDir, supervisorSocket)
+	statePath := filepath.Join(SupervisorDir, supervisorState)
+	exitPath := filepath.Join(SupervisorDir, supervisorExit)
+	os.Remove(sockPath)
+	os.Remove(exitPath)
+	os.Remove(filepath.Join(SupervisorDir, supervisorLog+".0"))
+	os.Remove(filepath.Join(SupervisorDir, supervisorLog+".1"))
+
+	// The panel going away must not take the server down
+	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
+
+	logFile, err := os.Create(filepath.Join(SupervisorDir, supervisorLog))
+	if err != nil {
+		return err
+	}
+	sup := newSupervisor(logFile)
+	defer func() { sup.log.Close() }()
+
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sockPath)
+
+	// Wait for the panel before launching java so no output is lost
+	firstClient := make(chan net.Conn, 1)
+	go sup.acceptLoop(listener, firstClient)
+	select {
+	case <-firstClient:
+	case <-time.After(firstClientTimeout):
+		listener.Close()
+		return fmt.Errorf("no client connected within %s", firstClientTimeout)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		writeJSONFile(exitPath, SupervisorExit{ExitCode: -1, Error: err.Error(), Time: time.Now()})
+		listener.Close()
+		sup.finish()
+		return err
+	}
+
+	sup.setStdin(stdin)
+	writeJSONFile(statePath, SupervisorState{
+		SupervisorPID: os.Getpid(),
+		JavaPID:       cmd.Process.Pid,
+		StartedAt:     time.Now(),
+	})
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := sup.publish(scanner.Text()); err != nil {
+			fmt.Fprintln(os.Stderr, "console.log:", err)
+		}
+	}
+
+	result := SupervisorExit{Time: time.Now()}
+	if err := cmd.Wait(); err != nil {
+		result.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+		result.Error = err.Error()
+	}
+
+	// exit.json must exist before clients see EOF
+	writeJSONFile(exitPath, result)
+	os.Remove(statePath)
+	listener.Close()
+	sup.finish()
+	return nil
+}
+
+// supervisor streams the output to its clients by following console.log: a client that reads
+// slowly falls behind instead of losing lines, and never blocks the server. One that falls
+// more than a whole segment behind is disconnected, the panel then resyncs from the log.
+type supervisor struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // Signaled on every line, rotation and client removal
+	stdin   io.WriteCloser
+	clients map[net.Conn]bool
+	log     *os.File
+	segment int   // Rotations of console.log so far
+	size    int64 // Bytes written to the current console.log
+	done    bool  // The output is over, clients leave once they sent everything
+}
+
+func newSupervisor(log *os.File) *supervisor {
+	s := &supervisor{clients: make(map[net.Conn]bool), log: log}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *supervisor) acceptLoop(listener net.Listener, firstClient chan net.Conn) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		// Clients get the output from the moment they connect
+		s.mu.Lock()
+		file, err := os.Open(filepath.Join(SupervisorDir, supervisorLog))
+		if err == nil {
+			_, err = file.Seek(s.size, io.SeekStart)
+		}
+		if err != nil || s.done {
+			s.mu.Unlock()
+			if file != nil {
+				file.Close()
+			}
+			conn.Close()
+			continue
+		}
+		s.clients[conn] = true
+		segment, offset := s.segment, s.size
+		s.mu.Unlock()
+
+		go s.writeLoop(conn, file, segment, offset)
+		go s.readLoop(conn)
+
+		select {
+		case firstClient <- conn:
+		default:
+		}
+	}
+}
+
+// writeLoop streams the console.log segment it is at to one client, then the next ones
+func (s *supervisor) writeLoop(conn net.Conn, file *os.File, segment int, offset int64) {
+	defer func() {
+		file.Close()
+		conn.Close()
+		s.removeClient(conn)
+	}()
+
+	for {
+		s.mu.Lock()
+		for s.clients[conn] && !s.done && s.segment == segment && s.size == offset {
+			s.cond.Wait()
+		}
+		connected, done, rotated, size := s.clients[conn], s.done, s.segment != segment, s.size
+		s.mu.Unlock()
+
+		switch {
+		case !connected:
+			return
+		case rotated:
+			// The segment is complete, send the rest of it and follow the new console.log
+			if _, err := io.Copy(conn, file); err != nil {
+				return
+			}
+			s.mu.Lock()
+			if s.segment != segment+1 {
+				// The next segment is gone already
+				s.mu.Unlock()
+				return
+			}
+			next, err := os.Open(filepath.Join(SupervisorDir, supervisorLog))
+			s.mu.Unlock()
+			if err != nil {
+				return
+			}
+			file.Close()
+			file, segment, offset = next, segment+1, 0
+		case size > offset:
+			if _, err := io.CopyN(conn, file, size-offset); err != nil {
+				return
+			}
+			offset = size
+		case done:
+			return
+		}
+	}
+}
+
+// readLoop forwards the client's lines to the java stdin
+func (s *supervisor) readLoop(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		s.mu.Lock()
+		stdin := s.stdin
+		s.mu.Unlock()
+		if stdin != nil {
+			io.WriteString(stdin, scanner.Text()+"\n")
+		}
+	}
+	s.removeClient(conn)
+}
+
+func (s *supervisor) setStdin(stdin io.WriteCloser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stdin = stdin
+}
+
+// publish appends a stdout line to console.log, where the clients pick it up
+func (s *supervisor) publish(line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.cond.Broadcast()
+
+	n, err := io.WriteString(s.log, line+"\n")
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if s.size >= maxConsoleSegment {
+		return s.rotateLocked()
+	}
+	return nil
+}
+
+// rotateLocked starts a new console.log. The first segment is kept as console.log.0 since it
+// holds the boot output, the later ones only as console.log.1 until the next rotation.
+func (s *supervisor) rotateLocked() error {
+	path := filepath.Join(SupervisorDir, supervisorLog)
+	archive := path + ".1"
+	if s.segment == 0 {
+		archive = path + ".0"
+	}
+	if err := os.Rename(path, archive); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		os.Rename(archive, path)
+		return err
+	}
+	s.log.Close()
+	s.log = file
+	s.segment++
+	s.size = 0
+	return nil
+}
+
+func (s *supervisor) removeClient(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, conn)
+	s.cond.Broadcast()
+}
+
+// finish ends the output and gives the clients some time to receive the rest of it
+func (s *supervisor) finish() {
+	s.mu.Lock()
+	s.done = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(clientDrainTimeout)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		remaining := len(s.clients)
+		s.mu.Unlock()
+		if remaining == 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// processAlive reports whether a pid exists (signal 0 only checks permissions and existence)
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return syscall.Kill(pid, 0) == nil
+}
@@ -0,0 +1,288 @@
+package minecraft
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// TickMethod is the command set used to measure the tick rate of a server
+type TickMethod string
+
+const (
+	TickUnsupported TickMethod = ""
+	TickPaper       TickMethod = "paper"   // tps + mspt
+	TickForge       TickMethod = "forge"   // forge tps
+	TickVanilla     TickMethod = "vanilla" // tick query, 1.20.3+
+)
+
+const (
+	tickInterval = 15 * time.Second
+	// Without RCON the commands and their output end up in the console, so ask less often
+	tickStdinInterval = time.Minute
+	tickCaptureWait   = 2 * time.Second
+	// A measurement older than this is not reported anymore
+	tickMaxAge = 2 * time.Minute
+)
+
+// TickStats is the tick health of a running server
+type TickStats struct {
+	TPS     float64    `json:"tps"` // Last minute for Paper, current otherwise
+	TPS5m   float64    `json:"tps_5m,omitempty"`
+	TPS15m  float64    `json:"tps_15m,omitempty"`
+	MSPT    float64    `json:"mspt"` // Average milliseconds per tick
+	MSPTMin float64    `json:"mspt_min,omitempty"`
+	MSPTMax float64    `json:"mspt_max,omitempty"`
+	Source  TickMethod `json:"source"`
+	Time    time.Time  `json:"time"`
+}
+
+var (
+	// TPS from last 1m, 5m, 15m: 20.0, 20.0, 20.0 (a * marks values capped to 20)
+	paperTPSRegex = regexp.MustCompile(`TPS from last 1m, 5m, 15m:\s*\*?([\d.]+),\s*\*?([\d.]+),\s*\*?([\d.]+)`)
+	// Server tick times (avg/min/max) from last 5s, 10s, 1m:
+	// ◴ 1.2/0.5/3.4, 1.3/0.5/4.0, 1.3/0.4/9.8
+	paperMSPTRegex = regexp.MustCompile(`([\d.]+)/([\d.]+)/([\d.]+)`)
+	// Forge 1.16 and older: Overall : Mean tick time: 12.345 ms. Mean TPS: 20.000
+	forgeLegacyTPSRegex = regexp.MustCompile(`Overall\s*: Mean tick time: ([\d.]+) ms\. Mean TPS: ([\d.]+)`)
+	// Forge 1.17+: Overall: 20.000 TPS (12.345 ms/tick)
+	forgeTPSRegex = regexp.MustCompile(`Overall\s*: ([\d.]+) TPS \(([\d.]+) ms/tick\)`)
+	// Target tick rate: 20.0 per second.
+	// Average time per tick: 1.2ms (Target: 50.0ms)
+	vanillaRateRegex = regexp.MustCompile(`Target tick rate: ([\d.]+) per second`)
+	vanillaMSPTRegex = regexp.MustCompile(`Average time per tick: ([\d.]+)ms`)
+)
+
+// tickCommand is a command to run and the pattern telling its output is complete
+type tickCommand struct {
+	command  string
+	complete *regexp.Regexp
+}
+
+var tickCommands = map[TickMethod][]tickCommand{
+	TickPaper:   {{"tps", paperTPSRegex}, {"mspt", paperMSPTRegex}},
+	TickForge:   {{"forge tps", regexp.MustCompile(`Overall\s*:`)}},
+	TickVanilla: {{"tick query", vanillaMSPTRegex}},
+}
+
+// TickMethodFor picks how to measure the tick rate of a server type and version
+func TickMethodFor(serverType core.ServerType, version string) TickMethod {
+	switch serverType {
+	case core.TypePaper:
+		return TickPaper
+	case core.TypeForge:
+		return TickForge
+	case core.TypeVanilla, core.TypeFabric:
+		if supportsTickQuery(version) {
+			return TickVanilla
+		}
+	}
+	return TickUnsupported
+}
+
+// supportsTickQuery reports whether the version has the /tick command (1.20.3+)
+func supportsTickQuery(version string) bool {
+	return versionAtLeast(version, 20, 3)
+}
+
+// versionAtLeast compares a release like 1.20.4 to 1.minor.patch. Year-based versions (26.1...)
+// are newer than any 1.x, snapshots are unknown and reported as older.
+func versionAtLeast(version string, minor, patch int) bool {
+	parts := strings.Split(version, ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) < 2 {
+		return false // Snapshots
+	}
+	if major > 1 {
+		return true
+	}
+	vMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	vPatch := 0
+	if len(parts) > 2 {
+		vPatch, _ = strconv.Atoi(parts[2])
+	}
+	return vMinor > minor || (vMinor == minor && vPatch >= patch)
+}
+
+// ParseTickOutput turns the output of the method's commands into stats
+func ParseTickOutput(method TickMethod, output string) (*TickStats, error) {
+	output = stripFormatting(StripANSI(output))
+	stats := &TickStats{Source: method, Time: time.Now()}
+
+	switch method {
+	case TickPaper:
+		loc := paperTPSRegex.FindStringSubmatchIndex(output)
+		if loc == nil {
+			return nil, fmt.Errorf("unexpected tps output: %q", output)
+		}
+		stats.TPS = parseFloat(output[loc[2]:loc[3]])
+		stats.TPS5m = parseFloat(output[loc[4]:loc[5]])
+		stats.TPS15m = parseFloat(output[loc[6]:loc[7]])
+		// The first triple after the TPS line covers the last 5 seconds
+		if ms := paperMSPTRegex.FindStringSubmatch(output[loc[1]:]); ms != nil {
+			stats.MSPT, stats.MSPTMin, stats.MSPTMax = parseFloat(ms[1]), parseFloat(ms[2]), parseFloat(ms[3])
+		}
+	case TickForge:
+		if m := forgeTPSRegex.FindStringSubmatch(output); m != nil {
+			stats.TPS, stats.MSPT = parseFloat(m[1]), parseFloat(m[2])
+		} else if m := forgeLegacyTPSRegex.FindStringSubmatch(output); m != nil {
+			stats.MSPT, stats.TPS = parseFloat(m[1]), parseFloat(m[2])
+		} else {
+			return nil, fmt.Errorf("unexpected forge tps output: %q", output)
+		}
+	case TickVanilla:
+		m := vanillaMSPTRegex.FindStringSubmatch(output)
+		if m == nil {
+			return nil, fmt.Errorf("unexpected tick query output: %q", output)
+		}
+		stats.MSPT = parseFloat(m[1])
+		target := 20.0
+		if r := vanillaRateRegex.FindStringSubmatch(output); r != nil {
+			target = parseFloat(r[1])
+		}
+		// The server never ticks faster than its target rate
+		stats.TPS = target
+		if stats.MSPT > 0 {
+			stats.TPS = math.Min(target, 1000/stats.MSPT)
+		}
+	default:
+		return nil, fmt.Errorf("tick measurement not supported")
+	}
+	return stats, nil
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func (i *Instance) SetTickMethod(method TickMethod) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tickMethod = method
+}
+
+// TickStats returns the last tick measurement, false when there is no recent one
+func (i *Instance) TickStats() (TickStats, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.tick == nil || time.Since(i.tick.Time) > tickMaxAge {
+		return TickStats{}, false
+	}
+	return *i.tick, true
+}
+
+// MeasureTick runs the tick commands of the server and parses their output.
+// RCON is preferred, stdin is used when it is unavailable.
+func (i *Instance) MeasureTick() (*TickStats, bool, error) {
+	i.mu.RLock()
+	method := i.tickMethod
+	i.mu.RUnlock()
+
+	commands, ok := tickCommands[method]
+	if !ok {
+		return nil, false, fmt.Errorf("tick measurement not supported")
+	}
+
+	var output strings.Builder
+	viaStdin := false
+	for _, c := range commands {
+		out, err := i.RCON(c.command)
+		if errors.Is(err, ErrRCONUnavailable) {
+			viaStdin = true
+			out, err = i.captureCommand(c.command, c.complete, tickCaptureWait)
+		}
+		if err != nil {
+			return nil, viaStdin, err
+		}
+		output.WriteString(out)
+		output.WriteString("\n")
+	}
+
+	stats, err := ParseTickOutput(method, output.String())
+	return stats, viaStdin, err
+}
+
+// captureCommand writes a command to stdin and collects the console lines that follow,
+// until complete matches them or the wait is over
+func (i *Instance) captureCommand(cmd string, complete *regexp.Regexp, wait time.Duration) (string, error) {
+	ch := i.Subscribe()
+	defer i.Unsubscribe(ch)
+
+	if err := i.SendCommand(cmd); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	timeout := time.After(wait)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return output.String(), nil
+			}
+			if msg.Type != "log" || msg.Log == nil {
+				continue
+			}
+			output.WriteString(msg.Log.Message)
+			output.WriteString("\n")
+			if complete.MatchString(stripFormatting(output.String())) {
+				return output.String(), nil
+			}
+		case <-timeout:
+			if output.Len() == 0 {
+				return "", fmt.Errorf("no answer to %q", cmd)
+			}
+			return output.String(), nil
+		}
+	}
+}
+
+// monitorTicks measures the tick rate while the process runs
+func (i *Instance) monitorTicks(exited chan struct{}) {
+	defer func() {
+		i.mu.Lock()
+		i.tick = nil
+		i.mu.Unlock()
+	}()
+
+	wait := tickInterval
+	for {
+		select {
+		case <-exited:
+			return
+		case <-time.After(wait):
+		}
+
+		wait = tickInterval
+		if i.GetStatus() != core.StatusRunning {
+			continue
+		}
+		i.mu.RLock()
+		method := i.tickMethod
+		i.mu.RUnlock()
+		if method == TickUnsupported {
+			continue
+		}
+
+		stats, viaStdin, err := i.MeasureTick()
+		if viaStdin {
+			wait = tickStdinInterval
+		}
+		if err != nil {
+			continue
+		}
+		i.mu.Lock()
+		i.tick = stats
+		i.mu.Unlock()
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"github.com/ZiplEix/crafteur/services"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
-func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *controller.FileController, playerCtrl *controller.PlayerController, logCtrl *controller.LogController, backupCtrl *controller.BackupController, schedulerCtrl *controller.SchedulerController, worldCtrl *controller.WorldController, addonCtrl *controller.AddonController, modrinthCtrl *controller.ModrinthController) {
+func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *controller.FileController, playerCtrl *controller.PlayerController, logCtrl *controller.LogController, backupCtrl *controller.BackupController, schedulerCtrl *controller.SchedulerController, worldCtrl *controller.WorldController, addonCtrl *controller.AddonController, modrinthCtrl *controller.ModrinthController, javaCtrl *controller.JavaController, portCtrl *controller.PortController, crashCtrl *controller.CrashController, metricsCtrl *controller.MetricsController) {
 	api := e.Group("/api")
 
+	// Prometheus scrape endpoint, authenticated by its own token rather than the session cookie
+	e.GET("/metrics", metricsCtrl.Export)
+
 	// Public Routes
 	api.POST("/login", controller.Login)
 
@@ -29,25 +33,59 @@ func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *c
 	protected.GET("/servers", serverCtrl.Index)
 	protected.GET("/servers/:id", serverCtrl.GetOne)
 	protected.POST("/servers", serverCtrl.Create)
+	protected.PUT("/servers/:id", serverCtrl.Update)
 	protected.DELETE("/servers/:id", serverCtrl.Delete)
+	protected.GET("/servers/:id/status", serverCtrl.Status)
 
 	protected.POST("/servers/:id/start", serverCtrl.Start)
 	protected.POST("/servers/:id/stop", serverCtrl.Stop)
+	protected.POST("/servers/:id/restart", serverCtrl.Restart)
 	protected.POST("/servers/:id/command", serverCtrl.Command)
+	protected.POST("/servers/:id/rcon", serverCtrl.RCON)
+	protected.GET("/servers/:id/console", serverCtrl.ConsoleHistory)
+	protected.GET("/servers/:id/metrics", metricsCtrl.Get)
 
 	protected.GET("/servers/:id/ws", serverCtrl.Console)
 	protected.GET("/servers/:id/properties", serverCtrl.GetProperties)
 	protected.POST("/servers/:id/properties", serverCtrl.UpdateProperties)
+	protected.GET("/servers/:id/restart-policy", serverCtrl.GetRestartPolicy)
+	protected.PUT("/servers/:id/restart-policy", serverCtrl.UpdateRestartPolicy)
+	protected.GET("/servers/:id/startup", serverCtrl.GetStartupSettings)
+	protected.PUT("/servers/:id/startup", serverCtrl.UpdateStartupSettings)
+	protected.GET("/servers/:id/stop-settings", serverCtrl.GetStopSettings)
+	protected.PUT("/servers/:id/stop-settings", serverCtrl.UpdateStopSettings)
+	protected.GET("/servers/:id/launch", serverCtrl.GetLaunchArgs)
+	protected.PUT("/servers/:id/launch", serverCtrl.UpdateLaunchArgs)
 
 	// Update endpoint
 	protected.GET("/meta/versions", serverCtrl.GetVersions)
+	protected.GET("/meta/jvm-presets", serverCtrl.GetJVMPresets)
+	protected.GET("/meta/ports", portCtrl.List)
+	protected.GET("/meta/ports/suggest", portCtrl.Suggest)
 	protected.POST("/servers/:id/version", serverCtrl.ChangeVersion)
 
 	// Player Routes
 	protected.GET("/servers/:id/players/cache", playerCtrl.GetCache)
+	protected.GET("/servers/:id/players/profile", playerCtrl.GetProfile)
 	protected.GET("/servers/:id/players/ops", playerCtrl.GetOps)
+	protected.POST("/servers/:id/players/ops", playerCtrl.SetOp)
+	protected.DELETE("/servers/:id/players/ops/:name", playerCtrl.RemoveOp)
 	protected.GET("/servers/:id/players/banned", playerCtrl.GetBanned)
+	protected.POST("/servers/:id/players/banned", playerCtrl.Ban)
+	protected.DELETE("/servers/:id/players/banned/:name", playerCtrl.Pardon)
+	protected.GET("/servers/:id/players/banned-ips", playerCtrl.GetBannedIPs)
+	protected.POST("/servers/:id/players/banned-ips", playerCtrl.BanIP)
+	protected.DELETE("/servers/:id/players/banned-ips/:ip", playerCtrl.PardonIP)
+	protected.GET("/servers/:id/players/whitelist", playerCtrl.GetWhitelist)
+	protected.POST("/servers/:id/players/whitelist", playerCtrl.AddToWhitelist)
+	protected.DELETE("/servers/:id/players/whitelist/:name", playerCtrl.RemoveFromWhitelist)
 	protected.POST("/servers/:id/players/action", playerCtrl.HandleAction)
+	protected.GET("/servers/:id/players/playtime", playerCtrl.GetPlaytime)
+	protected.GET("/servers/:id/players/sessions", playerCtrl.GetSessions)
+	protected.GET("/servers/:id/players/activity", playerCtrl.GetActivity)
+	protected.GET("/servers/:id/playerdata/:player", playerCtrl.GetPlayerData)
+	protected.GET("/servers/:id/playerdata/:player/stats", playerCtrl.GetPlayerStats)
+	protected.GET("/servers/:id/playerdata/:player/advancements", playerCtrl.GetPlayerAdvancements)
 
 	// File Routes
 	protected.GET("/servers/:id/files", fileCtrl.ListFiles)
@@ -59,6 +97,11 @@ func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *c
 	// Log Routes
 	protected.GET("/servers/:id/logs", logCtrl.ListLogs)
 	protected.GET("/servers/:id/logs/content", logCtrl.GetLogContent)
+	protected.GET("/servers/:id/logs/search", logCtrl.Search)
+
+	// Crash Routes
+	protected.GET("/servers/:id/crashes", crashCtrl.List)
+	protected.GET("/servers/:id/crashes/content", crashCtrl.Get)
 
 	// Backup Routes
 	protected.GET("/servers/:id/backups", backupCtrl.ListBackups)
@@ -74,8 +117,10 @@ func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *c
 	// World Routes
 	protected.GET("/servers/:id/worlds", worldCtrl.ListWorlds)
 	protected.POST("/servers/:id/worlds", worldCtrl.CreateWorld)
+	protected.POST("/servers/:id/worlds/import", worldCtrl.ImportWorld, middleware.BodyLimit("8G")) // services.MaxWorldArchiveSize
 	protected.POST("/servers/:id/worlds/:name/activate", worldCtrl.ActivateWorld)
 	protected.DELETE("/servers/:id/worlds/:name", worldCtrl.DeleteWorld)
+	protected.GET("/servers/:id/worlds/:name/export", worldCtrl.ExportWorld)
 
 	// Addon Routes
 	protected.GET("/servers/:id/addons/:type", addonCtrl.Index)
@@ -84,4 +129,12 @@ func Register(e *echo.Echo, serverCtrl *controller.ServerController, fileCtrl *c
 	// Modrinth Routes
 	protected.GET("/modrinth/search", modrinthCtrl.Search)
 	protected.POST("/modrinth/install", modrinthCtrl.Install)
+
+	// Java Runtime Routes
+	protected.GET("/java/runtimes", javaCtrl.ListRuntimes)
+	protected.POST("/java/runtimes", javaCtrl.InstallRuntime)
+	protected.PUT("/servers/:id/java", javaCtrl.AssignRuntime)
+
+	// Ports
+	protected.GET("/servers/:id/ports", portCtrl.GetServerPorts)
 }
@@ -9,6 +9,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ZiplEix/crafteur/minecraft"
 )
 
 type BackupEntry struct {
@@ -70,6 +72,9 @@ func (s *BackupService) CreateBackup(serverID string) error {
 		if info.Name() == "session.lock" {
 			return nil
 		}
+		if info.IsDir() && relPath == minecraft.SupervisorDir {
+			return filepath.SkipDir
+		}
 		if relPath == "." {
 			return nil
 		}
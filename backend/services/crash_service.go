@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+// CrashService indexes crash-reports/ and hs_err_pid*.log files. Parsed reports are cached
+// until the file changes.
+type CrashService struct {
+	basePath string
+
+	mu    sync.Mutex
+	cache map[string]cachedCrash // Keyed by absolute path
+}
+
+type cachedCrash struct {
+	modTime time.Time
+	report  minecraft.CrashReport
+}
+
+func NewCrashService(basePath string) *CrashService {
+	return &CrashService{
+		basePath: basePath,
+		cache:    make(map[string]cachedCrash),
+	}
+}
+
+// ListCrashes returns every crash of a server, newest first
+func (s *CrashService) ListCrashes(serverID string) ([]minecraft.CrashReport, error) {
+	if strings.Contains(serverID, "..") {
+		return nil, fmt.Errorf("invalid server ID")
+	}
+	runDir := filepath.Join(s.basePath, serverID)
+
+	files, err := minecraft.FindCrashFiles(runDir)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]minecraft.CrashReport, 0, len(files))
+	for _, f := range files {
+		report, err := s.parse(runDir, f)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// GetCrash returns one parsed report along with the raw file
+func (s *CrashService) GetCrash(serverID, name string) (*minecraft.CrashReport, string, error) {
+	if strings.Contains(serverID, "..") || strings.Contains(name, "..") {
+		return nil, "", fmt.Errorf("invalid path")
+	}
+	runDir := filepath.Join(s.basePath, serverID)
+
+	// Only files the index knows about can be read
+	files, err := minecraft.FindCrashFiles(runDir)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, f := range files {
+		if f != name {
+			continue
+		}
+		report, err := s.parse(runDir, f)
+		if err != nil {
+			return nil, "", err
+		}
+		content, err := os.ReadFile(filepath.Join(runDir, f))
+		if err != nil {
+			return nil, "", err
+		}
+		return report, string(content), nil
+	}
+	return nil, "", fmt.Errorf("crash report not found")
+}
+
+func (s *CrashService) parse(runDir, name string) (*minecraft.CrashReport, error) {
+	path, _ := filepath.Abs(filepath.Join(runDir, name))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[path]
+	s.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		report := cached.report
+		return &report, nil
+	}
+
+	report, err := minecraft.ParseCrashFile(runDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[path] = cachedCrash{modTime: info.ModTime(), report: *report}
+	s.mu.Unlock()
+	return report, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+// ExporterService renders the state of every server in the Prometheus text format
+type ExporterService struct {
+	servers   *ServerService
+	backups   *BackupService
+	scheduler *SchedulerService
+}
+
+func NewExporterService(servers *ServerService, backups *BackupService, scheduler *SchedulerService) *ExporterService {
+	return &ExporterService{
+		servers:   servers,
+		backups:   backups,
+		scheduler: scheduler,
+	}
+}
+
+// metricFamily is one metric with all its series, written as a single block
+type metricFamily struct {
+	name, kind, help string
+	series           []metricSeries
+}
+
+func (f *metricFamily) add(labels string, value float64) {
+	f.series = append(f.series, metricSeries{labels: labels, value: value})
+}
+
+type metricSeries struct {
+	labels string
+	value  float64
+}
+
+var serverStates = []core.ServerStatus{core.StatusStopped, core.StatusStarting, core.StatusRunning, core.StatusStopping, core.StatusCrashed}
+
+func (s *ExporterService) Write(w io.Writer) error {
+	configs, err := s.servers.GetAllServers()
+	if err != nil {
+		return err
+	}
+	sort.Slice(configs, func(a, b int) bool { return configs[a].ID < configs[b].ID })
+
+	var (
+		upFamily          = &metricFamily{name: "crafteur_server_up", kind: "gauge", help: "1 when the server is running."}
+		statusFamily      = &metricFamily{name: "crafteur_server_status", kind: "gauge", help: "Current status of the server, 1 for the active state."}
+		cpuFamily         = &metricFamily{name: "crafteur_server_cpu_percent", kind: "gauge", help: "CPU usage of the server process, as a share of all cores."}
+		rssFamily         = &metricFamily{name: "crafteur_server_memory_rss_bytes", kind: "gauge", help: "Resident memory of the server process."}
+		ramMaxFamily      = &metricFamily{name: "crafteur_server_memory_max_bytes", kind: "gauge", help: "Maximum Java heap (Xmx)."}
+		playersFamily     = &metricFamily{name: "crafteur_server_players_online", kind: "gauge", help: "Players currently connected."}
+		tpsFamily         = &metricFamily{name: "crafteur_server_tps", kind: "gauge", help: "Ticks per second, when it could be measured."}
+		msptFamily        = &metricFamily{name: "crafteur_server_mspt", kind: "gauge", help: "Average milliseconds per tick, when it could be measured."}
+		uptimeFamily      = &metricFamily{name: "crafteur_server_uptime_seconds", kind: "gauge", help: "Time since the server process started."}
+		restartsFamily    = &metricFamily{name: "crafteur_server_restarts_total", kind: "counter", help: "Automatic restarts since the panel started."}
+		backupCountFamily = &metricFamily{name: "crafteur_server_backups", kind: "gauge", help: "Number of backups."}
+		backupAgeFamily   = &metricFamily{name: "crafteur_server_backup_age_seconds", kind: "gauge", help: "Time since the latest backup."}
+		backupSizeFamily  = &metricFamily{name: "crafteur_server_backup_size_bytes", kind: "gauge", help: "Size of the latest backup."}
+		taskRunsFamily    = &metricFamily{name: "crafteur_scheduler_task_runs_total", kind: "counter", help: "Scheduled task executions since the panel started."}
+	)
+	families := []*metricFamily{upFamily, statusFamily, cpuFamily, rssFamily, ramMaxFamily, playersFamily, tpsFamily, msptFamily, uptimeFamily, restartsFamily, backupCountFamily, backupAgeFamily, backupSizeFamily, taskRunsFamily}
+
+	runs := s.scheduler.TaskRuns()
+	now := time.Now()
+	for _, cfg := range configs {
+		labels := formatLabels("id", cfg.ID, "name", cfg.Name, "type", string(cfg.Type), "version", cfg.Version)
+
+		status := core.StatusStopped
+		ramMax := float64(cfg.RAM) * 1024 * 1024
+		var cpu, rss, players, uptime, restarts float64
+		if inst, ok := s.servers.manager.GetInstance(cfg.ID); ok {
+			status = inst.GetStatus()
+			if stats, ok := inst.Stats(); ok {
+				cpu = stats.CpuUsage
+				rss = float64(stats.RamUsage)
+				if stats.RamMax > 0 {
+					ramMax = float64(stats.RamMax)
+				}
+			}
+			players = float64(inst.PlayerCount())
+			if tick, ok := inst.TickStats(); ok {
+				tpsFamily.add(labels, tick.TPS)
+				msptFamily.add(labels, tick.MSPT)
+			}
+			uptime = inst.GetUptime().Seconds()
+			restarts = float64(inst.GetRestartCount())
+		}
+
+		upFamily.add(labels, boolValue(status == core.StatusRunning))
+		for _, state := range serverStates {
+			statusFamily.add(labels+`,state="`+strings.ToLower(string(state))+`"`, boolValue(status == state))
+		}
+		cpuFamily.add(labels, cpu)
+		rssFamily.add(labels, rss)
+		ramMaxFamily.add(labels, ramMax)
+		playersFamily.add(labels, players)
+		uptimeFamily.add(labels, uptime)
+		restartsFamily.add(labels, restarts)
+
+		// Backups are listed newest first. Age and size are left out when there is none.
+		if backups, err := s.backups.ListBackups(cfg.ID); err == nil {
+			backupCountFamily.add(labels, float64(len(backups)))
+			if len(backups) > 0 {
+				backupAgeFamily.add(labels, now.Sub(backups[0].CreatedAt).Seconds())
+				backupSizeFamily.add(labels, float64(backups[0].Size))
+			}
+		}
+
+		for _, action := range []string{"start", "stop", "restart", "command"} {
+			for _, success := range []bool{true, false} {
+				result := "failure"
+				if success {
+					result = "success"
+				}
+				count := runs[TaskRunKey{ServerID: cfg.ID, Action: action, Success: success}]
+				taskRunsFamily.add(labels+`,action="`+action+`",result="`+result+`"`, float64(count))
+			}
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, series := range f.series {
+			fmt.Fprintf(out, "%s{%s} %g\n", f.name, series.labels, series.value)
+		}
+	}
+	return out.Flush()
+}
+
+// formatLabels renders name/value pairs as name="value", escaped for the text format
+func formatLabels(pairs ...string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(pairs)/2)
+	for idx := 0; idx+1 < len(pairs); idx += 2 {
+		parts = append(parts, pairs[idx]+`="`+escaper.Replace(pairs[idx+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -42,7 +42,7 @@ func (s *FabricService) GetLatestInstaller() (string, error) {
 	return "", fmt.Errorf("no fabric installer found")
 }
 
-func (s *FabricService) InstallFabric(serverDir string, mcVersion string, loaderVersion string) (string, error) {
+func (s *FabricService) InstallFabric(javaPath string, serverDir string, mcVersion string, loaderVersion string) (string, error) {
 	// 1. Get Installer URL
 	installerUrl, err := s.GetLatestInstaller()
 	if err != nil {
@@ -68,7 +68,7 @@ func (s *FabricService) InstallFabric(serverDir string, mcVersion string, loader
 		args = append(args, "-loader", loaderVersion)
 	}
 
-	cmd := exec.Command("java", args...)
+	cmd := exec.Command(javaPath, args...)
 	// Capture output for debugging if needed
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ZiplEix/crafteur/core"
+)
+
+var javaVersionRegex = regexp.MustCompile(`version "([^"]+)"`)
+
+// JavaService keeps track of the JDKs available to run servers: system ones under
+// /usr/lib/jvm, the java in PATH and the ones installed by crafteur in its runtimes directory.
+type JavaService struct {
+	systemDirs []string
+	installDir string
+
+	versions map[string]string // Cache of java binary path -> version
+	mu       sync.Mutex
+}
+
+func NewJavaService(installDir string, systemDirs ...string) *JavaService {
+	if len(systemDirs) == 0 {
+		systemDirs = []string{"/usr/lib/jvm"}
+	}
+	return &JavaService{
+		systemDirs: systemDirs,
+		installDir: installDir,
+		versions:   make(map[string]string),
+	}
+}
+
+func (s *JavaService) ListRuntimes() ([]core.JavaRuntime, error) {
+	runtimes := make([]core.JavaRuntime, 0)
+	seen := make(map[string]bool)
+
+	dirs := append([]string{s.installDir}, s.systemDirs...)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			home := filepath.Join(dir, entry.Name())
+			// /usr/lib/jvm is full of symlinks to the same JDK
+			resolved, err := filepath.EvalSymlinks(home)
+			if err != nil || seen[resolved] {
+				continue
+			}
+
+			rt, err := s.inspect(home)
+			if err != nil {
+				continue
+			}
+			seen[resolved] = true
+			rt.Managed = dir == s.installDir
+			runtimes = append(runtimes, *rt)
+		}
+	}
+
+	// The JDK in PATH may live anywhere (sdkman, /opt...)
+	if rt, err := s.pathRuntime(); err == nil && !seen[rt.Home] {
+		runtimes = append(runtimes, *rt)
+	}
+
+	sort.Slice(runtimes, func(i, j int) bool {
+		if runtimes[i].Major != runtimes[j].Major {
+			return runtimes[i].Major > runtimes[j].Major
+		}
+		return runtimes[i].ID < runtimes[j].ID
+	})
+	return runtimes, nil
+}
+
+// FindRuntime returns a runtime for the given Java major version, managed ones first.
+func (s *JavaService) FindRuntime(major int) (*core.JavaRuntime, error) {
+	runtimes, err := s.ListRuntimes()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *core.JavaRuntime
+	for i := range runtimes {
+		if runtimes[i].Major != major {
+			continue
+		}
+		if found == nil || (runtimes[i].Managed && !found.Managed) {
+			found = &runtimes[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no Java %d runtime installed", major)
+	}
+	return found, nil
+}
+
+// JavaPath returns the java binary to use for a major version. The one in PATH is only used
+// when no version is set (0), a version without installed runtime is an error.
+func (s *JavaService) JavaPath(major int) (string, error) {
+	if major == 0 {
+		return "java", nil
+	}
+	rt, err := s.FindRuntime(major)
+	if err != nil {
+		return "", err
+	}
+	return rt.Path, nil
+}
+
+func (s *JavaService) inspect(home string) (*core.JavaRuntime, error) {
+	javaPath := filepath.Join(home, "bin", "java")
+	info, err := os.Stat(javaPath)
+	if err != nil || info.IsDir() {
+		return nil, fmt.Errorf("no java binary in %s", home)
+	}
+
+	version, err := s.readVersion(home, javaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(javaPath)
+	if err != nil {
+		return nil, err
+	}
+	absHome, _ := filepath.Abs(home)
+
+	return &core.JavaRuntime{
+		ID:      filepath.Base(home),
+		Home:    absHome,
+		Path:    absPath,
+		Version: version,
+		Major:   parseJavaMajor(version),
+	}, nil
+}
+
+// pathRuntime describes the java found in PATH, its home is the parent of its bin directory
+func (s *JavaService) pathRuntime() (*core.JavaRuntime, error) {
+	javaPath, err := exec.LookPath("java")
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(javaPath)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return nil, err
+	}
+	home := filepath.Dir(filepath.Dir(resolved))
+
+	version, err := s.readVersion(home, resolved)
+	if err != nil {
+		return nil, err
+	}
+	return &core.JavaRuntime{
+		ID:      filepath.Base(home),
+		Home:    home,
+		Path:    resolved,
+		Version: version,
+		Major:   parseJavaMajor(version),
+	}, nil
+}
+
+// readVersion prefers the JDK's "release" file and only runs "java -version" without it
+func (s *JavaService) readVersion(home, javaPath string) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.versions[javaPath]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	version := ""
+	if file, err := os.Open(filepath.Join(home, "release")); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "JAVA_VERSION=") {
+				version = strings.Trim(strings.TrimPrefix(line, "JAVA_VERSION="), `"`)
+				break
+			}
+		}
+		file.Close()
+	}
+
+	if version == "" {
+		output, err := exec.Command(javaPath, "-version").CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("java -version failed: %w", err)
+		}
+		matches := javaVersionRegex.FindSubmatch(output)
+		if len(matches) < 2 {
+			return "", fmt.Errorf("unrecognized java -version output")
+		}
+		version = string(matches[1])
+	}
+
+	s.mu.Lock()
+	s.versions[javaPath] = version
+	s.mu.Unlock()
+	return version, nil
+}
+
+// parseJavaMajor handles both the old "1.8.0_392" and the new "17.0.9" schemes
+func parseJavaMajor(version string) int {
+	parts := strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == '+'
+	})
+	if len(parts) == 0 {
+		return 0
+	}
+	major, _ := strconv.Atoi(parts[0])
+	if major == 1 && len(parts) > 1 {
+		major, _ = strconv.Atoi(parts[1])
+	}
+	return major
+}
+
+// InstallArchive extracts a JDK archive (.tar.gz, .tgz or .zip) into the runtimes directory.
+func (s *JavaService) InstallArchive(archivePath, originalName string) (*core.JavaRuntime, error) {
+	if err := core.EnsureDir(s.installDir); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp(s.installDir, ".install-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := strings.ToLower(originalName)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		err = extractTarGz(archivePath, tmpDir)
+	case strings.HasSuffix(name, ".zip"):
+		err = extractZip(archivePath, tmpDir)
+	default:
+		return nil, fmt.Errorf("unsupported archive format, allowed: .tar.gz, .tgz, .zip")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	// JDK archives usually wrap everything in a jdk-17.0.9+9/ folder
+	home, err := findJavaHome(tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zips built on Windows carry no exec bit
+	binEntries, _ := os.ReadDir(filepath.Join(home, "bin"))
+	for _, entry := range binEntries {
+		if !entry.IsDir() {
+			os.Chmod(filepath.Join(home, "bin", entry.Name()), 0755)
+		}
+	}
+
+	rt, err := s.inspect(home)
+	if err != nil {
+		return nil, err
+	}
+
+	target := filepath.Join(s.installDir, fmt.Sprintf("jdk-%s", rt.Version))
+	if _, err := os.Stat(target); err == nil {
+		return nil, fmt.Errorf("runtime %s is already installed", filepath.Base(target))
+	}
+	if err := os.Rename(home, target); err != nil {
+		return nil, err
+	}
+
+	rt, err = s.inspect(target)
+	if err != nil {
+		return nil, err
+	}
+	rt.Managed = true
+	return rt, nil
+}
+
+// InstallUpload installs a JDK archive sent through the API
+func (s *JavaService) InstallUpload(fileHeader *multipart.FileHeader) (*core.JavaRuntime, error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "crafteur-jdk-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, src)
+	tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.InstallArchive(tmp.Name(), fileHeader.Filename)
+}
+
+func findJavaHome(root string) (string, error) {
+	var home string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if home != "" {
+			return filepath.SkipAll
+		}
+		if !d.IsDir() && d.Name() == "java" && filepath.Base(filepath.Dir(path)) == "bin" {
+			home = filepath.Dir(filepath.Dir(path))
+			// Skip the JRE bundled inside old JDK 8 layouts (jdk/jre/bin/java)
+			if filepath.Base(home) == "jre" {
+				if _, err := os.Stat(filepath.Join(filepath.Dir(home), "bin", "java")); err == nil {
+					home = filepath.Dir(home)
+				}
+			}
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if home == "" {
+		return "", fmt.Errorf("no bin/java found in archive")
+	}
+	return home, nil
+}
+
+// safeJoin protects against archive entries escaping the destination (ZipSlip)
+func safeJoin(dest, name string) (string, error) {
+	path := filepath.Join(dest, name)
+	if path != filepath.Clean(dest) && !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return path, nil
+}
+
+func extractTarGz(archivePath, dest string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// JDKs link files inside their own tree (e.g. legal/), never outside of it
+			if filepath.IsAbs(header.Linkname) {
+				continue
+			}
+			if _, err := safeJoin(dest, filepath.Join(filepath.Dir(header.Name), header.Linkname)); err != nil {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archivePath, dest string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		path, err := safeJoin(dest, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode()&0777)
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			out.Close()
+			return err
+		}
+		_, err = io.Copy(out, rc)
+		out.Close()
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
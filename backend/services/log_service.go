@@ -1,14 +1,20 @@
 package services
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ZiplEix/crafteur/minecraft"
 )
 
 type LogFileEntry struct {
@@ -68,36 +74,449 @@ func (s *LogService) ListLogFiles(serverID string) ([]LogFileEntry, error) {
 	return logs, nil
 }
 
-func (s *LogService) ReadLogFile(serverID, filename string) (string, error) {
-	if strings.Contains(serverID, "..") || strings.Contains(filename, "..") {
-		return "", fmt.Errorf("invalid path")
+// StreamLogEntries parses a whole log file into entries, stack traces folded into their record,
+// and calls emit for each of them. levels keeps only the given levels (e.g. WARN, ERROR), all of
+// them when empty. Only the entry being read is kept in memory.
+func (s *LogService) StreamLogEntries(ctx context.Context, serverID, filename string, levels []string, emit func(minecraft.LogEntry) error) (int, error) {
+	reader, _, err := s.openLog(serverID, filename)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	count := 0
+	send := func(entry minecraft.LogEntry) error {
+		if len(levels) > 0 && !containsFold(levels, entry.Level) {
+			return nil
+		}
+		count++
+		return emit(entry)
 	}
 
-	filePath := filepath.Join(s.basePath, serverID, "logs", filename)
+	var grouper minecraft.LogGrouper
+	scanner := newLogScanner(reader)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if lineNo%10000 == 0 && ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+		if entry, ok := grouper.Add(scanner.Text()); ok {
+			if err := send(entry); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	if entry, ok := grouper.Flush(); ok {
+		if err := send(entry); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// maxFullRead caps ReadLogFile, the beginning of bigger logs must be read by range
+const maxFullRead = 50 * 1024 * 1024
+
+// openLog opens a file of logs/, decompressing .gz files on the fly
+func (s *LogService) openLog(serverID, filename string) (io.ReadCloser, os.FileInfo, error) {
+	if strings.Contains(serverID, "..") || strings.Contains(filename, "..") || strings.ContainsAny(filename, "/\\") {
+		return nil, nil, fmt.Errorf("invalid path")
+	}
+
+	file, err := os.Open(filepath.Join(s.basePath, serverID, "logs", filename))
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
 
-	// Open file
-	file, err := os.Open(filePath)
+	if !strings.HasSuffix(filename, ".gz") {
+		return file, info, nil
+	}
+	gzReader, err := gzip.NewReader(file)
 	if err != nil {
-		return "", err
+		file.Close()
+		return nil, nil, err
 	}
-	defer file.Close()
+	return &gzipFile{Reader: gzReader, file: file}, info, nil
+}
 
-	var reader io.Reader = file
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
 
-	// Check if gzip
-	if strings.HasSuffix(filename, ".gz") {
-		gzReader, err := gzip.NewReader(file)
+// ReadLogFile returns a whole log file. Files over maxFullRead are cut to their last lines,
+// truncated is then true and the rest can be read with ReadLogRange.
+func (s *LogService) ReadLogFile(serverID, filename string) (string, bool, error) {
+	reader, info, err := s.openLog(serverID, filename)
+	if err != nil {
+		return "", false, err
+	}
+	defer reader.Close()
+
+	// Plain files skip straight to their end, compressed ones have to be read through
+	truncated := false
+	if file, ok := reader.(*os.File); ok && info.Size() > maxFullRead {
+		if _, err := file.Seek(info.Size()-maxFullRead, io.SeekStart); err != nil {
+			return "", false, err
+		}
+		truncated = true
+	}
+	content, cut, err := readTail(reader, maxFullRead)
+	if err != nil {
+		return "", false, err
+	}
+	if truncated || cut {
+		// Drop the partial first line
+		if idx := bytes.IndexByte(content, '\n'); idx >= 0 {
+			content = content[idx+1:]
+		}
+		truncated = true
+	}
+
+	return string(content), truncated, nil
+}
+
+// readTail reads r to the end and keeps its last max bytes, cut is true if some were dropped
+func readTail(r io.Reader, max int) ([]byte, bool, error) {
+	var buf []byte
+	cut := false
+	chunk := make([]byte, 1024*1024)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		// Shrinking only past twice the size keeps the copies rare
+		if len(buf) > 2*max {
+			buf = append(buf[:0], buf[len(buf)-max:]...)
+			cut = true
+		}
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return "", err
+			return nil, false, err
 		}
-		defer gzReader.Close()
-		reader = gzReader
 	}
+	if len(buf) > max {
+		buf = buf[len(buf)-max:]
+		cut = true
+	}
+	return buf, cut, nil
+}
+
+type LogRange struct {
+	Filename   string   `json:"filename"`
+	Offset     int      `json:"offset"` // Line number of the first line, 0-based
+	Lines      []string `json:"lines"`
+	NextOffset int      `json:"next_offset"`
+	EOF        bool     `json:"eof"`
+}
 
-	content, err := io.ReadAll(reader)
+const maxRangeLines = 5000
+
+// ReadLogRange reads `limit` lines starting at line `offset`, or the last `tail` lines when tail > 0.
+// The file is streamed, only the returned lines are kept in memory.
+func (s *LogService) ReadLogRange(serverID, filename string, offset, limit, tail int) (*LogRange, error) {
+	if limit <= 0 || limit > maxRangeLines {
+		limit = maxRangeLines
+	}
+	if tail > maxRangeLines {
+		tail = maxRangeLines
+	}
+
+	reader, _, err := s.openLog(serverID, filename)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer reader.Close()
 
-	return string(content), nil
+	result := &LogRange{Filename: filename, Offset: offset, Lines: []string{}}
+	scanner := newLogScanner(reader)
+	n := 0
+	for scanner.Scan() {
+		if tail > 0 {
+			// Ring of the last `tail` lines
+			if len(result.Lines) == tail {
+				result.Lines = result.Lines[1:]
+			}
+			result.Lines = append(result.Lines, scanner.Text())
+		} else if n >= offset {
+			if len(result.Lines) == limit {
+				result.NextOffset = n
+				return result, nil
+			}
+			result.Lines = append(result.Lines, scanner.Text())
+		}
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if tail > 0 {
+		result.Offset = n - len(result.Lines)
+	}
+	result.NextOffset = n
+	result.EOF = true
+	return result, nil
+}
+
+func newLogScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+type LogSearchOptions struct {
+	Pattern *regexp.Regexp // nil matches every line
+	Since   time.Time
+	Until   time.Time
+	Levels  []string
+	Context int // Lines before and after each match
+	Limit   int
+	File    string // Only search this file
+}
+
+type LogMatch struct {
+	File   string    `json:"file"`
+	Line   int       `json:"line"` // 1-based
+	Time   time.Time `json:"time,omitempty"`
+	Level  string    `json:"level,omitempty"`
+	Text   string    `json:"text"`
+	Before []string  `json:"before,omitempty"`
+	After  []string  `json:"after,omitempty"`
+}
+
+type LogSearchSummary struct {
+	Matches   int  `json:"matches"`
+	Files     int  `json:"files"`
+	Truncated bool `json:"truncated"`
+}
+
+// Archived logs are named after their day: 2024-01-05-1.log.gz
+var logFileDateRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-\d+\.log`)
+
+// SearchLogs scans every file of logs/ (newest first) and calls emit for each match as soon as its
+// context is complete. It stops at opts.Limit matches or when ctx is cancelled.
+func (s *LogService) SearchLogs(ctx context.Context, serverID string, opts LogSearchOptions, emit func(LogMatch) error) (*LogSearchSummary, error) {
+	files, err := s.ListLogFiles(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+
+	summary := &LogSearchSummary{}
+	for _, f := range files {
+		if opts.File != "" && f.Name != opts.File {
+			continue
+		}
+		if !strings.HasSuffix(f.Name, ".log") && !strings.HasSuffix(f.Name, ".log.gz") {
+			continue
+		}
+
+		// A file is written until its last modification, skip the ones that ended before the range
+		if !opts.Since.IsZero() && f.ModTime.Before(opts.Since) {
+			continue
+		}
+		day, ok := logFileDay(f)
+		if !ok {
+			// The modification day is the last day of the file, go back one day per midnight in it
+			midnights, err := s.countMidnights(serverID, f.Name)
+			if err != nil {
+				return summary, err
+			}
+			day = day.AddDate(0, 0, -midnights)
+		}
+		if !opts.Until.IsZero() && day.After(opts.Until) {
+			continue
+		}
+
+		summary.Files++
+		done, err := s.searchFile(ctx, serverID, f.Name, day, opts, summary, emit)
+		if err != nil {
+			return summary, err
+		}
+		if done {
+			break
+		}
+	}
+	return summary, nil
+}
+
+// logFileDay returns the day a log file starts on. latest.log has no date in its name, its
+// modification day is returned with false: that is the day it ends on.
+func logFileDay(f LogFileEntry) (time.Time, bool) {
+	if m := logFileDateRegex.FindStringSubmatch(f.Name); m != nil {
+		if day, err := time.ParseInLocation("2006-01-02", m[1], time.Local); err == nil {
+			return day, true
+		}
+	}
+	y, mo, d := f.ModTime.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, time.Local), false
+}
+
+// countMidnights counts how many times the clock of a log file goes backwards
+func (s *LogService) countMidnights(serverID, filename string) (int, error) {
+	reader, _, err := s.openLog(serverID, filename)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	var parser minecraft.LogParser
+	midnights := 0
+	lastClock := time.Duration(-1)
+	scanner := newLogScanner(reader)
+	for scanner.Scan() {
+		if clock, ok := parseClock(parser.Parse(scanner.Text()).Time); ok {
+			if lastClock >= 0 && clock < lastClock {
+				midnights++
+			}
+			lastClock = clock
+		}
+	}
+	return midnights, scanner.Err()
+}
+
+// searchFile returns true when the search must stop (limit reached or cancelled)
+func (s *LogService) searchFile(ctx context.Context, serverID, filename string, day time.Time, opts LogSearchOptions, summary *LogSearchSummary, emit func(LogMatch) error) (bool, error) {
+	reader, _, err := s.openLog(serverID, filename)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	var parser minecraft.LogParser
+	before := make([]string, 0, opts.Context)
+	pending := []*LogMatch{}
+	lastClock := time.Duration(-1)
+
+	flush := func(all bool) error {
+		kept := pending[:0]
+		for _, m := range pending {
+			if all || len(m.After) >= opts.Context {
+				if err := emit(*m); err != nil {
+					return err
+				}
+				continue
+			}
+			kept = append(kept, m)
+		}
+		pending = kept
+		return nil
+	}
+
+	scanner := newLogScanner(reader)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if lineNo%10000 == 0 && ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+		text := scanner.Text()
+		parsed := parser.Parse(text)
+
+		// Line time: file day + printed clock, a clock going backwards means midnight passed
+		var lineTime time.Time
+		if clock, ok := parseClock(parsed.Time); ok {
+			if lastClock >= 0 && clock < lastClock {
+				day = day.AddDate(0, 0, 1)
+			}
+			lastClock = clock
+			lineTime = day.Add(clock)
+		}
+
+		for _, m := range pending {
+			if len(m.After) < opts.Context {
+				m.After = append(m.After, text)
+			}
+		}
+		if err := flush(false); err != nil {
+			return true, err
+		}
+
+		if !lineTime.IsZero() && !opts.Until.IsZero() && lineTime.After(opts.Until) {
+			break
+		}
+
+		matched := !summary.Truncated &&
+			(opts.Pattern == nil || opts.Pattern.MatchString(parsed.Message) || opts.Pattern.MatchString(text)) &&
+			(len(opts.Levels) == 0 || containsFold(opts.Levels, parsed.Level)) &&
+			(opts.Since.IsZero() || (!lineTime.IsZero() && !lineTime.Before(opts.Since)))
+		if matched && summary.Matches >= opts.Limit {
+			// One match past the limit: there really are more than Limit
+			summary.Truncated = true
+		} else if matched {
+			summary.Matches++
+			pending = append(pending, &LogMatch{
+				File:   filename,
+				Line:   lineNo,
+				Time:   lineTime,
+				Level:  parsed.Level,
+				Text:   text,
+				Before: append([]string{}, before...),
+			})
+			if err := flush(false); err != nil {
+				return true, err
+			}
+		}
+
+		if opts.Context > 0 {
+			if len(before) == opts.Context {
+				before = before[1:]
+			}
+			before = append(before, text)
+		}
+
+		if summary.Truncated && len(pending) == 0 {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	if err := flush(true); err != nil {
+		return true, err
+	}
+	if summary.Truncated {
+		return true, nil
+	}
+	return false, nil
+}
+
+// parseClock reads the HH:MM:SS a log line was printed at
+func parseClock(value string) (time.Duration, bool) {
+	if i := strings.LastIndex(value, " "); i >= 0 {
+		value = value[i+1:] // Forge debug.log: 05Jan2024 12:00:00.123
+	}
+	if i := strings.Index(value, "."); i >= 0 {
+		value = value[:i]
+	}
+	t, err := time.Parse("15:04:05", value)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+const (
+	metricsSampleInterval = 10 * time.Second
+	metricsRollupInterval = time.Minute
+	// Disk usage walks the whole server directory, it is refreshed less often than the rest
+	metricsDiskInterval = 5 * time.Minute
+	metricsMaxPoints    = 5000
+)
+
+// metricsTier is one level of the history: raw samples, then averages over Resolution seconds
+type metricsTier struct {
+	Resolution int           // Storage key, 0 for raw samples
+	Step       time.Duration // Time between two points
+	Retention  time.Duration
+}
+
+var metricsTiers = []metricsTier{
+	{Resolution: 0, Step: metricsSampleInterval, Retention: 24 * time.Hour},
+	{Resolution: 60, Step: time.Minute, Retention: 7 * 24 * time.Hour},
+	{Resolution: 900, Step: 15 * time.Minute, Retention: 90 * 24 * time.Hour},
+}
+
+type MetricsSeries struct {
+	From       int64               `json:"from"`
+	To         int64               `json:"to"`
+	Step       int64               `json:"step"`       // Seconds between two points
+	Resolution int64               `json:"resolution"` // Seconds per stored sample the points were built from
+	Points     []core.MetricSample `json:"points"`
+}
+
+// MetricsService records the resources of every running instance into SQLite and
+// downsamples the history as it ages.
+type MetricsService struct {
+	manager *minecraft.Manager
+	stop    chan struct{}
+
+	diskMu sync.Mutex
+	disk   map[string]*diskUsage
+}
+
+type diskUsage struct {
+	size       uint64
+	measuredAt time.Time
+	measuring  bool
+}
+
+func NewMetricsService(manager *minecraft.Manager) *MetricsService {
+	return &MetricsService{
+		manager: manager,
+		stop:    make(chan struct{}),
+		disk:    make(map[string]*diskUsage),
+	}
+}
+
+func (s *MetricsService) Start() {
+	// Catch up on rollups missed while the panel was down
+	s.rollup(time.Now(), true)
+
+	go func() {
+		sampleTicker := time.NewTicker(metricsSampleInterval)
+		rollupTicker := time.NewTicker(metricsRollupInterval)
+		defer sampleTicker.Stop()
+		defer rollupTicker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-sampleTicker.C:
+				s.sample(now)
+			case now := <-rollupTicker.C:
+				s.rollup(now, false)
+			}
+		}
+	}()
+}
+
+func (s *MetricsService) Stop() {
+	close(s.stop)
+}
+
+func (s *MetricsService) sample(now time.Time) {
+	for _, inst := range s.manager.Instances() {
+		stats, ok := inst.Stats()
+		if !ok {
+			continue
+		}
+		m := &core.MetricSample{
+			ServerID: inst.ID,
+			Time:     now.Unix(),
+			Cpu:      stats.CpuUsage,
+			CpuPeak:  stats.CpuUsage,
+			Ram:      stats.RamUsage,
+			RamPeak:  stats.RamUsage,
+			RamMax:   stats.RamMax,
+			Threads:  int(stats.Threads),
+			Disk:     s.diskUsage(inst),
+			Players:  inst.PlayerCount(),
+		}
+		if stats.Tick != nil {
+			m.TPS, m.MSPT = &stats.Tick.TPS, &stats.Tick.MSPT
+		}
+		if err := database.InsertMetric(m); err != nil {
+			fmt.Printf("Erreur enregistrement métriques %s: %v\n", inst.ID, err)
+		}
+	}
+}
+
+// diskUsage returns the last measured size of the server directory, refreshing it in the
+// background when it is stale
+func (s *MetricsService) diskUsage(inst *minecraft.Instance) uint64 {
+	s.diskMu.Lock()
+	defer s.diskMu.Unlock()
+
+	usage, ok := s.disk[inst.ID]
+	if !ok {
+		usage = &diskUsage{}
+		s.disk[inst.ID] = usage
+	}
+	if !usage.measuring && time.Since(usage.measuredAt) >= metricsDiskInterval {
+		usage.measuring = true
+		go func(dir string) {
+			size, err := getDirSize(dir)
+			s.diskMu.Lock()
+			defer s.diskMu.Unlock()
+			usage.measuring = false
+			usage.measuredAt = time.Now()
+			if err == nil {
+				usage.size = uint64(size)
+			}
+		}(inst.RunDir)
+	}
+	return usage.size
+}
+
+// rollup aggregates every finished bucket into the next tier and drops expired samples.
+// The recent window is recomputed each time, full rebuilds the whole retention of each tier.
+func (s *MetricsService) rollup(now time.Time, full bool) {
+	for idx := 1; idx < len(metricsTiers); idx++ {
+		source, target := metricsTiers[idx-1], metricsTiers[idx]
+		res := int64(target.Resolution)
+
+		window := 2 * target.Step
+		if full {
+			window = source.Retention
+		}
+		since := now.Add(-window).Unix() / res * res
+		before := now.Unix() / res * res
+		if err := database.RollupMetrics(source.Resolution, target.Resolution, since, before); err != nil {
+			fmt.Printf("Erreur agrégation métriques (%ds): %v\n", target.Resolution, err)
+		}
+	}
+
+	for _, tier := range metricsTiers {
+		if err := database.PruneMetrics(tier.Resolution, now.Add(-tier.Retention).Unix()); err != nil {
+			fmt.Printf("Erreur purge métriques (%ds): %v\n", tier.Resolution, err)
+		}
+	}
+}
+
+// Query returns the history of a server between from and to. The finest tier still covering
+// from is used, and points are grouped by step (0 picks one giving a readable number of points).
+func (s *MetricsService) Query(serverID string, from, to time.Time, step time.Duration) (*MetricsSeries, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-time.Hour)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if step < 0 {
+		return nil, fmt.Errorf("invalid step")
+	}
+
+	span := to.Sub(from)
+	if step == 0 {
+		step = span / 500
+	}
+	if minStep := span / metricsMaxPoints; step < minStep {
+		step = minStep
+	}
+
+	tier := metricsTiers[len(metricsTiers)-1]
+	now := time.Now()
+	for _, t := range metricsTiers {
+		if !from.Before(now.Add(-t.Retention)) {
+			tier = t
+			break
+		}
+	}
+	// Points can't be finer than the stored samples, and must be a multiple of them
+	if step < tier.Step {
+		step = tier.Step
+	}
+	step = step.Round(tier.Step)
+
+	points, err := database.QueryMetrics(serverID, tier.Resolution, int64(step.Seconds()), from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsSeries{
+		From:       from.Unix(),
+		To:         to.Unix(),
+		Step:       int64(step.Seconds()),
+		Resolution: int64(tier.Step.Seconds()),
+		Points:     points,
+	}, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+var (
+	ErrNoPlayerData = errors.New("no data saved for this player")
+
+	uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+)
+
+// GetPlayerData returns the position, health, xp and inventories saved in the world.
+// player is a name or a UUID, world defaults to the active one.
+func (s *PlayerService) GetPlayerData(serverID, player, world string) (*minecraft.PlayerData, error) {
+	worldDir, uuid, err := s.playerFiles(serverID, player, world)
+	if err != nil {
+		return nil, err
+	}
+	data, err := minecraft.ReadPlayerData(worldDir, uuid)
+	return data, noPlayerData(err)
+}
+
+// GetPlayerStats returns the statistics of stats/<uuid>.json
+func (s *PlayerService) GetPlayerStats(serverID, player, world string) (*minecraft.PlayerStats, error) {
+	worldDir, uuid, err := s.playerFiles(serverID, player, world)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := minecraft.ReadPlayerStats(worldDir, uuid)
+	return stats, noPlayerData(err)
+}
+
+// GetPlayerAdvancements returns the advancements of advancements/<uuid>.json
+func (s *PlayerService) GetPlayerAdvancements(serverID, player, world string, recipes bool) ([]minecraft.Advancement, error) {
+	worldDir, uuid, err := s.playerFiles(serverID, player, world)
+	if err != nil {
+		return nil, err
+	}
+	advancements, err := minecraft.ReadPlayerAdvancements(worldDir, uuid, recipes)
+	return advancements, noPlayerData(err)
+}
+
+// playerFiles returns the world directory and the UUID the player's files are named after
+func (s *PlayerService) playerFiles(serverID, player, world string) (string, string, error) {
+	serverDir := filepath.Join(s.DataDir, "servers", serverID)
+	if world == "" {
+		world = "world"
+		if props, err := minecraft.LoadProperties(filepath.Join(serverDir, "server.properties")); err == nil && props["level-name"] != "" {
+			world = props["level-name"]
+		}
+	}
+	if world != filepath.Base(world) || world == ".." {
+		return "", "", fmt.Errorf("invalid world name")
+	}
+	worldDir := filepath.Join(serverDir, world)
+	if _, err := os.Stat(worldDir); err != nil {
+		return "", "", fmt.Errorf("world %s not found", world)
+	}
+
+	if uuidRegex.MatchString(player) {
+		return worldDir, minecraft.FormatUUID(player), nil
+	}
+	profile, err := s.resolvePlayer(serverID, player)
+	if err != nil {
+		return "", "", err
+	}
+	return worldDir, minecraft.FormatUUID(profile.UUID), nil
+}
+
+func noPlayerData(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoPlayerData
+	}
+	return err
+}
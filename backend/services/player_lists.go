@@ -0,0 +1,500 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+// Whitelist, ops and bans are edited in their JSON files while the server is stopped. A running
+// server keeps them in memory and rewrites the files, so it is driven with commands instead.
+
+var (
+	ErrUnknownPlayer = errors.New("unknown player")
+	ErrServerBusy    = errors.New("server is starting or stopping, try again in a moment")
+	ErrOfflineOnly   = errors.New("only possible while the server is stopped")
+)
+
+// CommandRejectedError is returned when the server refused a command
+type CommandRejectedError struct {
+	Command  string
+	Response string
+}
+
+func (e *CommandRejectedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Response)
+}
+
+// Vanilla answers with one of these when a player command did nothing
+var rejectedPrefixes = []string{
+	"Nothing changed",
+	"No player was found",
+	"That player does not exist",
+	"Player is already whitelisted",
+	"Player is not whitelisted",
+	"Unknown or incomplete command",
+	"Incorrect argument",
+	"Invalid IP address",
+	"Could not",
+}
+
+func CommandRejected(response string) bool {
+	for _, prefix := range rejectedPrefixes {
+		if strings.HasPrefix(response, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PlayerService) GetWhitelist(serverID string) ([]minecraft.WhitelistEntry, error) {
+	whitelist := []minecraft.WhitelistEntry{}
+	if err := s.readFile(serverID, "whitelist.json", &whitelist); err != nil {
+		return nil, err
+	}
+	return whitelist, nil
+}
+
+func (s *PlayerService) GetBannedIPs(serverID string) ([]minecraft.IPBanEntry, error) {
+	banned := []minecraft.IPBanEntry{}
+	if err := s.readFile(serverID, "banned-ips.json", &banned); err != nil {
+		return nil, err
+	}
+	return banned, nil
+}
+
+func (s *PlayerService) AddToWhitelist(serverID, name string) (*minecraft.WhitelistEntry, error) {
+	if err := CheckPlayerName(name); err != nil {
+		return nil, err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.resolvePlayer(serverID, name)
+	if errors.Is(err, ErrUnknownPlayer) && online {
+		// The server looks the profile up itself
+		if _, err := s.runCommand(inst, "whitelist add "+name); err != nil {
+			return nil, err
+		}
+		return &minecraft.WhitelistEntry{Name: name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	whitelist, err := s.GetWhitelist(serverID)
+	if err != nil {
+		return nil, err
+	}
+	entry := minecraft.WhitelistEntry{UUID: profile.UUID, Name: profile.Name}
+	if idx := findByName(whitelist, name, func(e minecraft.WhitelistEntry) string { return e.Name }); idx >= 0 {
+		whitelist[idx] = entry
+	} else {
+		whitelist = append(whitelist, entry)
+	}
+	if err := s.writeFile(serverID, "whitelist.json", whitelist); err != nil {
+		return nil, err
+	}
+	if online {
+		if _, err := s.runCommand(inst, "whitelist reload"); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+func (s *PlayerService) RemoveFromWhitelist(serverID, name string) error {
+	if err := CheckPlayerName(name); err != nil {
+		return err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return err
+	}
+	whitelist, err := s.GetWhitelist(serverID)
+	if err != nil {
+		return err
+	}
+	idx := findByName(whitelist, name, func(e minecraft.WhitelistEntry) string { return e.Name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s is not whitelisted", ErrUnknownPlayer, name)
+	}
+	whitelist = append(whitelist[:idx], whitelist[idx+1:]...)
+	if err := s.writeFile(serverID, "whitelist.json", whitelist); err != nil {
+		return err
+	}
+	if online {
+		_, err = s.runCommand(inst, "whitelist reload")
+	}
+	return err
+}
+
+// SetOp adds an operator or updates its level. A level of 0 uses op-permission-level.
+func (s *PlayerService) SetOp(serverID string, op minecraft.OpEntry) (*minecraft.OpEntry, error) {
+	if err := CheckPlayerName(op.Name); err != nil {
+		return nil, err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defaultLevel := s.defaultOpLevel(serverID)
+	if op.Level == 0 {
+		op.Level = defaultLevel
+	}
+	if op.Level < 1 || op.Level > 4 {
+		return nil, fmt.Errorf("level must be between 1 and 4")
+	}
+
+	if online {
+		// The op command always grants op-permission-level
+		if op.Level != defaultLevel || op.BypassesPlayerLimit {
+			return nil, fmt.Errorf("custom level or bypassesPlayerLimit: %w", ErrOfflineOnly)
+		}
+		if _, err := s.runCommand(inst, "op "+op.Name); err != nil {
+			return nil, err
+		}
+		return &op, nil
+	}
+
+	profile, err := s.resolvePlayer(serverID, op.Name)
+	if err != nil {
+		return nil, err
+	}
+	op.UUID, op.Name = profile.UUID, profile.Name
+
+	ops, err := s.GetOps(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if idx := findByName(ops, op.Name, func(e minecraft.OpEntry) string { return e.Name }); idx >= 0 {
+		ops[idx] = op
+	} else {
+		ops = append(ops, op)
+	}
+	if err := s.writeFile(serverID, "ops.json", ops); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+func (s *PlayerService) RemoveOp(serverID, name string) error {
+	if err := CheckPlayerName(name); err != nil {
+		return err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return err
+	}
+	if online {
+		_, err := s.runCommand(inst, "deop "+name)
+		return err
+	}
+
+	ops, err := s.GetOps(serverID)
+	if err != nil {
+		return err
+	}
+	idx := findByName(ops, name, func(e minecraft.OpEntry) string { return e.Name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s is not an operator", ErrUnknownPlayer, name)
+	}
+	return s.writeFile(serverID, "ops.json", append(ops[:idx], ops[idx+1:]...))
+}
+
+// BanPlayer bans a player. expires is empty for a permanent ban.
+func (s *PlayerService) BanPlayer(serverID, name, reason string, expires time.Time) (*minecraft.BanEntry, error) {
+	if err := CheckPlayerName(name); err != nil {
+		return nil, err
+	}
+	if err := CheckReason(reason); err != nil {
+		return nil, err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return nil, err
+	}
+	entry := newBanEntry(reason, expires)
+	entry.Name = name
+
+	if online {
+		// Temporary bans have no command
+		if !expires.IsZero() {
+			return nil, fmt.Errorf("temporary ban: %w", ErrOfflineOnly)
+		}
+		if _, err := s.runCommand(inst, strings.TrimSpace("ban "+name+" "+reason)); err != nil {
+			return nil, err
+		}
+		return &entry, nil
+	}
+
+	profile, err := s.resolvePlayer(serverID, name)
+	if err != nil {
+		return nil, err
+	}
+	entry.UUID, entry.Name = profile.UUID, profile.Name
+
+	banned, err := s.GetBanned(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if idx := findByName(banned, name, func(e minecraft.BanEntry) string { return e.Name }); idx >= 0 {
+		banned[idx] = entry
+	} else {
+		banned = append(banned, entry)
+	}
+	if err := s.writeFile(serverID, "banned-players.json", banned); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *PlayerService) PardonPlayer(serverID, name string) error {
+	if err := CheckPlayerName(name); err != nil {
+		return err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return err
+	}
+	if online {
+		_, err := s.runCommand(inst, "pardon "+name)
+		return err
+	}
+
+	banned, err := s.GetBanned(serverID)
+	if err != nil {
+		return err
+	}
+	idx := findByName(banned, name, func(e minecraft.BanEntry) string { return e.Name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s is not banned", ErrUnknownPlayer, name)
+	}
+	return s.writeFile(serverID, "banned-players.json", append(banned[:idx], banned[idx+1:]...))
+}
+
+func (s *PlayerService) BanIP(serverID, ip, reason string, expires time.Time) (*minecraft.IPBanEntry, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("invalid IP address %q", ip)
+	}
+	if err := CheckReason(reason); err != nil {
+		return nil, err
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return nil, err
+	}
+	ban := newBanEntry(reason, expires)
+	entry := minecraft.IPBanEntry{IP: ip, Created: ban.Created, Source: ban.Source, Expires: ban.Expires, Reason: ban.Reason}
+
+	if online {
+		if !expires.IsZero() {
+			return nil, fmt.Errorf("temporary ban: %w", ErrOfflineOnly)
+		}
+		if _, err := s.runCommand(inst, strings.TrimSpace("ban-ip "+ip+" "+reason)); err != nil {
+			return nil, err
+		}
+		return &entry, nil
+	}
+
+	banned, err := s.GetBannedIPs(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if idx := findByName(banned, ip, func(e minecraft.IPBanEntry) string { return e.IP }); idx >= 0 {
+		banned[idx] = entry
+	} else {
+		banned = append(banned, entry)
+	}
+	if err := s.writeFile(serverID, "banned-ips.json", banned); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *PlayerService) PardonIP(serverID, ip string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("invalid IP address %q", ip)
+	}
+	s.listsMu.Lock()
+	defer s.listsMu.Unlock()
+
+	inst, online, err := s.listTarget(serverID)
+	if err != nil {
+		return err
+	}
+	if online {
+		_, err := s.runCommand(inst, "pardon-ip "+ip)
+		return err
+	}
+
+	banned, err := s.GetBannedIPs(serverID)
+	if err != nil {
+		return err
+	}
+	idx := findByName(banned, ip, func(e minecraft.IPBanEntry) string { return e.IP })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s is not banned", ErrUnknownPlayer, ip)
+	}
+	return s.writeFile(serverID, "banned-ips.json", append(banned[:idx], banned[idx+1:]...))
+}
+
+// listTarget tells whether the lists of a server must be edited through commands
+func (s *PlayerService) listTarget(serverID string) (*minecraft.Instance, bool, error) {
+	inst, exists := s.Manager.GetInstance(serverID)
+	if !exists {
+		return nil, false, fmt.Errorf("serveur introuvable")
+	}
+	switch inst.GetStatus() {
+	case core.StatusRunning:
+		return inst, true, nil
+	case core.StatusStopped, core.StatusCrashed:
+		return inst, false, nil
+	default:
+		return nil, false, ErrServerBusy
+	}
+}
+
+// runCommand sends a command over RCON, or stdin when RCON is unavailable (no response then)
+func (s *PlayerService) runCommand(inst *minecraft.Instance, cmd string) (string, error) {
+	// A line break would start another command on stdin
+	if strings.ContainsAny(cmd, "\r\n") {
+		return "", fmt.Errorf("invalid command %q", cmd)
+	}
+	response, err := inst.RCON(cmd)
+	if errors.Is(err, minecraft.ErrRCONUnavailable) {
+		return "", inst.SendCommand(cmd)
+	}
+	if err != nil {
+		return "", err
+	}
+	// List edits are idempotent, being already in the wanted state is fine
+	alreadyDone := strings.HasPrefix(response, "Nothing changed") || strings.HasPrefix(response, "Player is already whitelisted")
+	if CommandRejected(response) && !alreadyDone {
+		return "", &CommandRejectedError{Command: cmd, Response: response}
+	}
+	return response, nil
+}
+
+// resolvePlayer finds the profile of a player: from the server's usercache.json when they already
+// joined, else through the profile resolver. Players seen on another server are the last resort
+// when Mojang can't be reached.
+func (s *PlayerService) resolvePlayer(serverID, name string) (*core.PlayerProfile, error) {
+	cache := []minecraft.PlayerCacheEntry{}
+	if err := s.readFile(serverID, "usercache.json", &cache); err != nil {
+		return nil, err
+	}
+	for _, entry := range cache {
+		if strings.EqualFold(entry.Name, name) {
+			return &core.PlayerProfile{UUID: entry.UUID, Name: entry.Name}, nil
+		}
+	}
+
+	profile, err := s.profiles.ResolveName(serverID, name)
+	if err == nil || errors.Is(err, ErrUnknownPlayer) {
+		return profile, err
+	}
+	uuid, dbErr := database.FindPlayerUUID(name)
+	if dbErr != nil || uuid == "" {
+		return nil, err
+	}
+	return &core.PlayerProfile{UUID: uuid, Name: name}, nil
+}
+
+func (s *PlayerService) defaultOpLevel(serverID string) int {
+	props, err := minecraft.LoadProperties(filepath.Join(s.DataDir, "servers", serverID, "server.properties"))
+	if err != nil {
+		return 4
+	}
+	if level, err := strconv.Atoi(props["op-permission-level"]); err == nil {
+		return level
+	}
+	return 4
+}
+
+// writeFile replaces a server JSON file, formatted like the server does
+func (s *PlayerService) writeFile(serverID, filename string, v any) error {
+	path := filepath.Join(s.DataDir, "servers", serverID, filename)
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Names and reasons end up in console commands, they are checked before anything is sent
+func CheckPlayerName(name string) error {
+	if !playerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid player name %q", name)
+	}
+	return nil
+}
+
+func CheckReason(reason string) error {
+	if strings.ContainsAny(reason, "\r\n") {
+		return fmt.Errorf("the reason must fit on one line")
+	}
+	return nil
+}
+
+func newBanEntry(reason string, expires time.Time) minecraft.BanEntry {
+	entry := minecraft.BanEntry{
+		Created: time.Now().Format(minecraft.BanTimeFormat),
+		Source:  "Server",
+		Expires: "forever",
+		Reason:  reason,
+	}
+	if entry.Reason == "" {
+		entry.Reason = "Banned by an operator."
+	}
+	if !expires.IsZero() {
+		entry.Expires = expires.Format(minecraft.BanTimeFormat)
+	}
+	return entry
+}
+
+func findByName[T any](entries []T, name string, key func(T) string) int {
+	for idx, e := range entries {
+		if strings.EqualFold(key(e), name) {
+			return idx
+		}
+	}
+	return -1
+}
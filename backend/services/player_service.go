@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/ZiplEix/crafteur/core"
 	"github.com/ZiplEix/crafteur/minecraft"
 )
 
@@ -17,15 +19,30 @@ type PlayerCacheItem struct {
 }
 
 type PlayerService struct {
-	Manager *minecraft.Manager
-	DataDir string
+	Manager  *minecraft.Manager
+	DataDir  string
+	profiles *ProfileService
+
+	listsMu sync.Mutex // Serializes whitelist, ops and bans edits
 }
 
-func NewPlayerService(manager *minecraft.Manager, dataDir string) *PlayerService {
+func NewPlayerService(manager *minecraft.Manager, dataDir string, profiles *ProfileService) *PlayerService {
 	return &PlayerService{
-		Manager: manager,
-		DataDir: dataDir,
+		Manager:  manager,
+		DataDir:  dataDir,
+		profiles: profiles,
+	}
+}
+
+// ResolveProfile maps a name (or a UUID when name is empty) to a player profile
+func (s *PlayerService) ResolveProfile(serverID, name, uuid string) (*core.PlayerProfile, error) {
+	if name != "" {
+		return s.profiles.ResolveName(serverID, name)
+	}
+	if !s.profiles.OnlineMode(serverID) {
+		return nil, fmt.Errorf("offline-mode UUIDs can't be mapped back to a name")
 	}
+	return s.profiles.LookupUUID(uuid)
 }
 
 func (s *PlayerService) readFile(serverID, filename string, v interface{}) error {
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+// Sessions overlapping the requested range are loaded at once to compute the activity
+const maxActivitySessions = 100000
+
+// recordPlayerEvent stores the joins and leaves of a server in player_sessions
+func recordPlayerEvent(serverID string, event minecraft.PlayerEvent) {
+	var err error
+	switch event.Type {
+	case minecraft.PlayerJoin:
+		err = database.OpenPlayerSession(&core.PlayerSession{
+			ServerID: serverID,
+			Name:     event.Name,
+			UUID:     event.UUID,
+			IP:       event.IP,
+			JoinedAt: event.Time.Unix(),
+		})
+	case minecraft.PlayerLeave:
+		err = database.ClosePlayerSession(serverID, event.Name, event.Reason, event.Time.Unix())
+	}
+	if err != nil {
+		fmt.Printf("Erreur enregistrement session %s (%s): %v\n", event.Name, serverID, err)
+	}
+}
+
+func (s *PlayerService) GetPlaytimes(serverID string) ([]core.PlayerPlaytime, error) {
+	return database.GetPlayerPlaytimes(serverID, time.Now().Unix())
+}
+
+// GetSessions returns the sessions overlapping [from, to], newest first
+func (s *PlayerService) GetSessions(serverID, player string, from, to time.Time, limit int) ([]core.PlayerSession, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return database.GetPlayerSessions(serverID, player, from.Unix(), to.Unix(), limit)
+}
+
+// GetActivity computes the peak of concurrent players and the daily active players over [from, to]
+func (s *PlayerService) GetActivity(serverID string, from, to time.Time) (*core.PlayerActivity, error) {
+	now := time.Now()
+	if to.IsZero() || to.After(now) {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	sessions, err := database.GetPlayerSessions(serverID, "", from.Unix(), to.Unix(), maxActivitySessions)
+	if err != nil {
+		return nil, err
+	}
+
+	type change struct {
+		at    int64
+		delta int
+	}
+	changes := make([]change, 0, 2*len(sessions))
+
+	players := map[string]map[string]bool{} // Date -> names
+	for _, session := range sessions {
+		start, end := session.JoinedAt, session.LeftAt
+		if end == 0 {
+			end = to.Unix()
+		}
+		start, end = max(start, from.Unix()), min(end, to.Unix())
+		changes = append(changes, change{start, 1}, change{end, -1})
+
+		for day := startOfDay(time.Unix(start, 0).In(from.Location())); day.Unix() <= end; day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			if players[date] == nil {
+				players[date] = map[string]bool{}
+			}
+			players[date][session.Name] = true
+		}
+	}
+
+	// Leaves before joins at the same second, a reconnection isn't two players
+	sort.Slice(changes, func(a, b int) bool {
+		if changes[a].at != changes[b].at {
+			return changes[a].at < changes[b].at
+		}
+		return changes[a].delta < changes[b].delta
+	})
+	activity := &core.PlayerActivity{DailyActive: []core.DailyActivePlayers{}}
+	current := 0
+	for _, c := range changes {
+		current += c.delta
+		if current > activity.PeakConcurrent {
+			activity.PeakConcurrent = current
+			activity.PeakAt = c.at
+		}
+	}
+
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		activity.DailyActive = append(activity.DailyActive, core.DailyActivePlayers{Date: date, Players: len(players[date])})
+	}
+	return activity, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
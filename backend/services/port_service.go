@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+const DefaultGamePort = 25565
+
+type PortAllocation struct {
+	ServerID   string `json:"server_id"`
+	ServerName string `json:"server_name"`
+	Port       int    `json:"port"`
+	Protocol   string `json:"protocol"` // tcp or udp
+	Usage      string `json:"usage"`    // game, rcon or query
+	Free       bool   `json:"free"`     // Nothing is bound to it on the host right now
+}
+
+// PortConflictError is returned when a requested port is taken, with the next free one when known.
+type PortConflictError struct {
+	Port      int
+	Reason    string
+	Suggested int
+}
+
+func (e *PortConflictError) Error() string {
+	if e.Suggested > 0 {
+		return fmt.Sprintf("port %d %s (next free port: %d)", e.Port, e.Reason, e.Suggested)
+	}
+	return fmt.Sprintf("port %d %s", e.Port, e.Reason)
+}
+
+func IsPortConflict(err error) (*PortConflictError, bool) {
+	var conflict *PortConflictError
+	ok := errors.As(err, &conflict)
+	return conflict, ok
+}
+
+// PortService knows which ports every server uses (DB + server.properties) and
+// checks them against what is actually bound on the host.
+type PortService struct {
+	manager *minecraft.Manager
+	dataDir string
+}
+
+func NewPortService(manager *minecraft.Manager, dataDir string) *PortService {
+	return &PortService{
+		manager: manager,
+		dataDir: dataDir,
+	}
+}
+
+// serverPorts lists the game, RCON and query ports of one server
+func (s *PortService) serverPorts(cfg *core.ServerConfig) []PortAllocation {
+	props, err := minecraft.LoadProperties(filepath.Join(s.dataDir, cfg.ID, "server.properties"))
+	if err != nil {
+		props = map[string]string{}
+	}
+
+	alloc := func(port int, protocol, usage string) PortAllocation {
+		return PortAllocation{ServerID: cfg.ID, ServerName: cfg.Name, Port: port, Protocol: protocol, Usage: usage}
+	}
+
+	gamePort := propInt(props, "server-port", cfg.Port)
+	ports := []PortAllocation{alloc(gamePort, "tcp", "game")}
+	if cfg.Port != 0 && cfg.Port != gamePort {
+		// DB and server.properties disagree, both are reserved until they are synced
+		ports = append(ports, alloc(cfg.Port, "tcp", "game"))
+	}
+	if props["enable-rcon"] == "true" {
+		ports = append(ports, alloc(propInt(props, "rcon.port", 25575), "tcp", "rcon"))
+	}
+	if props["enable-query"] == "true" {
+		ports = append(ports, alloc(propInt(props, "query.port", gamePort), "udp", "query"))
+	}
+	return ports
+}
+
+func propInt(props map[string]string, key string, def int) int {
+	if v, err := strconv.Atoi(props[key]); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// ServerAllocations returns the ports claimed by one server
+func (s *PortService) ServerAllocations(serverID string) ([]PortAllocation, error) {
+	cfg, err := database.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := s.serverPorts(cfg)
+	for i := range allocations {
+		allocations[i].Free = IsPortFree(allocations[i].Port, allocations[i].Protocol)
+	}
+	return allocations, nil
+}
+
+// ListAllocations returns the ports claimed by every server
+func (s *PortService) ListAllocations() ([]PortAllocation, error) {
+	configs, err := database.GetAllServers()
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := make([]PortAllocation, 0)
+	for i := range configs {
+		for _, a := range s.serverPorts(&configs[i]) {
+			a.Free = IsPortFree(a.Port, a.Protocol)
+			allocations = append(allocations, a)
+		}
+	}
+	return allocations, nil
+}
+
+// IsPortFree tries to bind the port on all interfaces
+func IsPortFree(port int, protocol string) bool {
+	addr := fmt.Sprintf(":%d", port)
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// CheckAvailable returns an error if a TCP port is claimed by another server or busy on the host.
+// excludeID skips the server being edited.
+func (s *PortService) CheckAvailable(port int, excludeID string) error {
+	if port < 1024 || port > 65535 {
+		return fmt.Errorf("port must be between 1024 and 65535")
+	}
+
+	configs, err := database.GetAllServers()
+	if err != nil {
+		return err
+	}
+	for i := range configs {
+		if configs[i].ID == excludeID {
+			continue
+		}
+		for _, a := range s.serverPorts(&configs[i]) {
+			if a.Port == port && a.Protocol == "tcp" {
+				return s.conflictError(port, fmt.Sprintf("is already used by server %s (%s)", a.ServerName, a.Usage))
+			}
+		}
+	}
+
+	// The edited server may be the one holding the port right now
+	if inst, ok := s.manager.GetInstance(excludeID); ok && inst.GetStatus() != core.StatusStopped && inst.GetStatus() != core.StatusCrashed {
+		return nil
+	}
+	if !IsPortFree(port, "tcp") {
+		return s.conflictError(port, "is already in use by another process on the host")
+	}
+	return nil
+}
+
+// claimedByOther reports whether a TCP port is in the settings of a server other than excludeID
+func (s *PortService) claimedByOther(port int, excludeID string) (bool, error) {
+	configs, err := database.GetAllServers()
+	if err != nil {
+		return false, err
+	}
+	for i := range configs {
+		if configs[i].ID == excludeID {
+			continue
+		}
+		for _, a := range s.serverPorts(&configs[i]) {
+			if a.Port == port && a.Protocol == "tcp" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *PortService) conflictError(port int, reason string) error {
+	conflict := &PortConflictError{Port: port, Reason: reason}
+	if next, err := s.SuggestPort(port + 1); err == nil {
+		conflict.Suggested = next
+	}
+	return conflict
+}
+
+// SuggestPort returns the first port from `from` that no server claims and that is free on the host
+func (s *PortService) SuggestPort(from int) (int, error) {
+	if from < 1024 {
+		from = DefaultGamePort
+	}
+
+	claimed := make(map[int]bool)
+	configs, err := database.GetAllServers()
+	if err != nil {
+		return 0, err
+	}
+	for i := range configs {
+		for _, a := range s.serverPorts(&configs[i]) {
+			claimed[a.Port] = true
+		}
+	}
+
+	for port := from; port <= 65535; port++ {
+		if !claimed[port] && IsPortFree(port, "tcp") && IsPortFree(port, "udp") {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found from %d", from)
+}
+
+// CheckStart verifies that every port a stopped server needs can be bound
+func (s *PortService) CheckStart(serverID string) error {
+	cfg, err := database.GetServer(serverID)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range s.serverPorts(cfg) {
+		if IsPortFree(a.Port, a.Protocol) {
+			continue
+		}
+
+		// Name the culprit when it is one of ours
+		allocations, _ := s.ListAllocations()
+		for _, other := range allocations {
+			if other.ServerID == serverID || other.Port != a.Port || other.Protocol != a.Protocol {
+				continue
+			}
+			if inst, ok := s.manager.GetInstance(other.ServerID); ok && inst.GetStatus() != core.StatusStopped {
+				return fmt.Errorf("cannot start: %s port %d/%s is used by running server %s", a.Usage, a.Port, a.Protocol, other.ServerName)
+			}
+		}
+		return fmt.Errorf("cannot start: %s port %d/%s is already in use on the host", a.Usage, a.Port, a.Protocol)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+const (
+	DefaultMojangAPI  = "https://api.mojang.com"
+	DefaultProfileTTL = 24 * time.Hour
+)
+
+var playerNameRegex = regexp.MustCompile(`^\w{1,16}$`)
+
+// ProfileService maps player names to UUIDs and back. Mojang answers are cached in SQLite,
+// offline-mode servers get the UUID the server computes itself.
+type ProfileService struct {
+	baseURL string
+	ttl     time.Duration
+	client  *http.Client
+	dataDir string
+}
+
+// NewProfileService uses Mojang's API at baseURL (DefaultMojangAPI when empty)
+func NewProfileService(baseURL string, ttl time.Duration, dataDir string) *ProfileService {
+	if baseURL == "" {
+		baseURL = DefaultMojangAPI
+	}
+	if ttl <= 0 {
+		ttl = DefaultProfileTTL
+	}
+	return &ProfileService{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		ttl:     ttl,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		dataDir: dataDir,
+	}
+}
+
+// OnlineMode reads online-mode from server.properties, true when unset like the server does
+func (s *ProfileService) OnlineMode(serverID string) bool {
+	props, err := minecraft.LoadProperties(filepath.Join(s.dataDir, "servers", serverID, "server.properties"))
+	if err != nil {
+		return true
+	}
+	return props["online-mode"] != "false"
+}
+
+// ResolveName returns the profile a server would give the player called name
+func (s *ProfileService) ResolveName(serverID, name string) (*core.PlayerProfile, error) {
+	if !playerNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid player name %q", name)
+	}
+	if !s.OnlineMode(serverID) {
+		return &core.PlayerProfile{UUID: minecraft.OfflineUUID(name), Name: name, Offline: true}, nil
+	}
+	return s.LookupName(name)
+}
+
+// LookupName finds the Mojang account called name, from the cache while it is fresh
+func (s *ProfileService) LookupName(name string) (*core.PlayerProfile, error) {
+	cached, err := database.GetProfileByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && s.fresh(cached) {
+		return cached, nil
+	}
+
+	profile, err := s.fetch("/users/profiles/minecraft/" + url.PathEscape(name))
+	if err != nil {
+		// Stale is better than nothing when Mojang is unreachable
+		if cached != nil && !errors.Is(err, ErrUnknownPlayer) {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// LookupUUID finds the current name of a Mojang account
+func (s *ProfileService) LookupUUID(uuid string) (*core.PlayerProfile, error) {
+	uuid = minecraft.FormatUUID(uuid)
+	cached, err := database.GetProfileByUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && s.fresh(cached) {
+		return cached, nil
+	}
+
+	profile, err := s.fetch("/user/profile/" + strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		if cached != nil && !errors.Is(err, ErrUnknownPlayer) {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *ProfileService) fresh(p *core.PlayerProfile) bool {
+	return time.Since(time.Unix(p.FetchedAt, 0)) < s.ttl
+}
+
+// fetch queries a profile endpoint and caches the answer
+func (s *ProfileService) fetch(path string) (*core.PlayerProfile, error) {
+	resp, err := s.client.Get(s.baseURL + path)
+	if err != nil {
+		return nil, fmt.Errorf("mojang api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent:
+		return nil, fmt.Errorf("%w: no Mojang account matches", ErrUnknownPlayer)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("mojang api: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("mojang api: %w", err)
+	}
+	if body.ID == "" || body.Name == "" {
+		return nil, fmt.Errorf("%w: no Mojang account matches", ErrUnknownPlayer)
+	}
+
+	profile := &core.PlayerProfile{UUID: minecraft.FormatUUID(body.ID), Name: body.Name, FetchedAt: time.Now().Unix()}
+	if err := database.SaveProfile(profile); err != nil {
+		fmt.Printf("Erreur cache profil %s: %v\n", profile.Name, err)
+	}
+	return profile, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -16,6 +17,15 @@ type SchedulerService struct {
 	serverService *ServerService
 	entryMap      map[string]cron.EntryID // Maps TaskID to CronEntryID
 	mu            sync.Mutex
+
+	runs   map[TaskRunKey]uint64 // Executions since the panel started
+	runsMu sync.Mutex
+}
+
+type TaskRunKey struct {
+	ServerID string
+	Action   string
+	Success  bool
 }
 
 func NewSchedulerService(serverService *ServerService) *SchedulerService {
@@ -23,6 +33,7 @@ func NewSchedulerService(serverService *ServerService) *SchedulerService {
 		cron:          cron.New(),
 		serverService: serverService,
 		entryMap:      make(map[string]cron.EntryID),
+		runs:          make(map[TaskRunKey]uint64),
 	}
 }
 
@@ -91,12 +102,7 @@ func (s *SchedulerService) executeTask(task *core.ScheduledTask) {
 		err = s.serverService.StopServer(task.ServerID)
 		// Wait a bit if we want to restart later? No, restart is a separate action or handled by script.
 	case "restart":
-		// Stop, wait, start
-		err = s.serverService.StopServer(task.ServerID)
-		if err == nil {
-			time.Sleep(5 * time.Second)
-			err = s.serverService.StartServer(task.ServerID)
-		}
+		err = s.serverService.RestartServer(context.Background(), task.ServerID, 0)
 	case "command":
 		commands := strings.Split(task.Payload, "\n")
 		for _, cmd := range commands {
@@ -114,6 +120,10 @@ func (s *SchedulerService) executeTask(task *core.ScheduledTask) {
 		fmt.Printf("Erreur exécution tâche %s: %v\n", task.ID, err)
 	}
 
+	s.runsMu.Lock()
+	s.runs[TaskRunKey{ServerID: task.ServerID, Action: task.Action, Success: err == nil}]++
+	s.runsMu.Unlock()
+
 	// Update LastRun
 	database.UpdateLastRun(task.ID, time.Now())
 
@@ -124,6 +134,18 @@ func (s *SchedulerService) executeTask(task *core.ScheduledTask) {
 	}
 }
 
+// TaskRuns returns how many tasks ran since the panel started, by server, action and outcome
+func (s *SchedulerService) TaskRuns() map[TaskRunKey]uint64 {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	runs := make(map[TaskRunKey]uint64, len(s.runs))
+	for k, v := range s.runs {
+		runs[k] = v
+	}
+	return runs
+}
+
 func (s *SchedulerService) CreateTask(task *core.ScheduledTask) error {
 	if err := database.CreateTask(task); err != nil {
 		return err
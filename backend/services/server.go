@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/ZiplEix/crafteur/core"
 	"github.com/ZiplEix/crafteur/database"
@@ -18,15 +21,19 @@ type ServerService struct {
 	fileService *FileService
 	fabric      *FabricService
 	paper       *PaperService
+	java        *JavaService
+	ports       *PortService
 }
 
-func NewServerService(m *minecraft.Manager, v *VersionService, f *FileService, fab *FabricService, pap *PaperService) *ServerService {
+func NewServerService(m *minecraft.Manager, v *VersionService, f *FileService, fab *FabricService, pap *PaperService, java *JavaService, ports *PortService) *ServerService {
 	return &ServerService{
 		manager:     m,
 		vService:    v,
 		fileService: f,
 		fabric:      fab,
 		paper:       pap,
+		java:        java,
+		ports:       ports,
 	}
 }
 
@@ -48,18 +55,143 @@ func (s *ServerService) LoadServersAtStartup() error {
 		}
 
 		inst := s.manager.AddInstance(cfg.ID, runDir, cfg.JarName)
-		inst.SetRAM(cfg.RAM)
+		s.applyConfig(inst, &cfg)
+
+		// Servers keep running under their supervisor while the panel is down
+		reattached, err := inst.Reattach()
+		if err != nil {
+			fmt.Printf(" -> Serveur %s (ID: %s) : reconnexion impossible: %v\n", cfg.Name, cfg.ID, err)
+		} else if reattached {
+			fmt.Printf(" -> Serveur %s (ID: %s) toujours actif, reconnecté (%s)\n", cfg.Name, cfg.ID, inst.GetStatus())
+		}
+		// Joins and leaves that happened while the panel was down were not recorded
+		if err := database.SyncPlayerSessions(cfg.ID, inst.OnlinePlayers(), time.Now().Unix()); err != nil {
+			fmt.Printf(" -> Serveur %s (ID: %s) : sessions joueurs: %v\n", cfg.Name, cfg.ID, err)
+		}
 
 		fmt.Printf(" -> Serveur chargé : %s (ID: %s)\n", cfg.Name, cfg.ID)
-		_ = inst
 	}
 	return nil
 }
 
+// applyConfig pushes the persisted settings of a server onto its runtime instance
+func (s *ServerService) applyConfig(inst *minecraft.Instance, cfg *core.ServerConfig) {
+	// Before every start (auto-restarts included): make sure RCON is on, then refuse
+	// to start when a port is held by something else
+	serverID := cfg.ID
+	inst.PreStart = func() error {
+		if err := s.resolveJava(inst); err != nil {
+			return err
+		}
+		if err := s.ensureRCON(inst); err != nil {
+			return fmt.Errorf("rcon setup failed: %w", err)
+		}
+		return s.ports.CheckStart(serverID)
+	}
+	s.loadRCON(inst)
+	inst.OnPlayerEvent = func(event minecraft.PlayerEvent) {
+		recordPlayerEvent(serverID, event)
+	}
+
+	if javaPath, err := s.java.JavaPath(cfg.JavaVersion); err == nil {
+		inst.SetJavaPath(javaPath)
+	} else {
+		fmt.Printf(" -> Serveur %s : %v, il ne pourra pas démarrer\n", cfg.ID, err)
+	}
+	inst.SetRAM(cfg.RAM)
+	inst.SetTickMethod(minecraft.TickMethodFor(cfg.Type, cfg.Version))
+	inst.SetLaunchArgs(cfg.JVMArgs, cfg.ServerArgs)
+	inst.SetRestartPolicy(cfg.RestartPolicy, cfg.RestartMaxRetries, time.Duration(cfg.RestartBackoff)*time.Second)
+	if err := inst.SetReadiness(cfg.ReadyPattern, time.Duration(cfg.StartupTimeout)*time.Second); err != nil {
+		fmt.Printf(" -> Serveur %s : %v, détection par défaut utilisée\n", cfg.ID, err)
+		inst.SetReadiness("", time.Duration(cfg.StartupTimeout)*time.Second)
+	}
+	inst.SetStopTimeouts(time.Duration(cfg.StopTimeout)*time.Second, time.Duration(cfg.TermGrace)*time.Second)
+}
+
+// resolveJava points the instance at the runtime of the server's Java version, installed
+// runtimes are looked up again on every start so a newly installed one is picked up
+func (s *ServerService) resolveJava(inst *minecraft.Instance) error {
+	cfg, err := database.GetServer(inst.ID)
+	if err != nil {
+		return err
+	}
+	javaPath, err := s.java.JavaPath(cfg.JavaVersion)
+	if err != nil {
+		return fmt.Errorf("cannot start: %w (install it or pick another Java version)", err)
+	}
+	inst.SetJavaPath(javaPath)
+	return nil
+}
+
+// ensureRCON enables RCON in server.properties (free port, generated password) and points the instance at it
+func (s *ServerService) ensureRCON(inst *minecraft.Instance) error {
+	propsPath := filepath.Join(inst.RunDir, "server.properties")
+	props, err := minecraft.LoadProperties(propsPath)
+	if err != nil {
+		return err
+	}
+
+	// The server writes rcon.port=25575 on its first run, so servers created before RCON was
+	// managed, or imported, all share it: keep it only when no other server claims it and it is
+	// free on the host. Called before start, the port can't be held by this server itself.
+	changed := false
+	port, err := strconv.Atoi(props["rcon.port"])
+	if err == nil {
+		claimed, claimErr := s.ports.claimedByOther(port, inst.ID)
+		if claimErr != nil {
+			return claimErr
+		}
+		if claimed || !IsPortFree(port, "tcp") {
+			err = fmt.Errorf("rcon port %d is taken", port)
+		}
+	}
+	if err != nil {
+		if port, err = s.ports.SuggestPort(minecraft.DefaultRCONPort); err != nil {
+			return err
+		}
+		props["rcon.port"] = strconv.Itoa(port)
+		changed = true
+	}
+	if minecraft.EnsureRCONProperties(props, port) || changed {
+		if err := minecraft.SaveProperties(propsPath, props); err != nil {
+			return err
+		}
+	}
+
+	s.loadRCON(inst)
+	return nil
+}
+
+// loadRCON reads the RCON settings of server.properties without changing them
+func (s *ServerService) loadRCON(inst *minecraft.Instance) {
+	props, err := minecraft.LoadProperties(filepath.Join(inst.RunDir, "server.properties"))
+	if err != nil || props["enable-rcon"] != "true" {
+		inst.SetRCON(0, "")
+		return
+	}
+	port, _ := strconv.Atoi(props["rcon.port"])
+	if port == 0 {
+		port = minecraft.DefaultRCONPort
+	}
+	inst.SetRCON(port, props["rcon.password"])
+}
+
 func (s *ServerService) CreateNewServer(name string, sType core.ServerType, port int, ram int, version string, importFile *multipart.FileHeader) (*core.ServerConfig, error) {
 	newID := uuid.New().String()
 	serverPath := filepath.Join("./data/servers", newID)
 
+	// 0. Port: pick the next free one when none is given
+	if port == 0 {
+		suggested, err := s.ports.SuggestPort(DefaultGamePort)
+		if err != nil {
+			return nil, err
+		}
+		port = suggested
+	} else if err := s.ports.CheckAvailable(port, ""); err != nil {
+		return nil, err
+	}
+
 	// 1. Validate version and get URL
 	downloadUrl, err := s.vService.GetDownloadURL(version)
 	if err != nil {
@@ -78,10 +210,17 @@ func (s *ServerService) CreateNewServer(name string, sType core.ServerType, port
 		return nil, fmt.Errorf("téléchargement server.jar échoué: %w", err)
 	}
 
+	javaVersion := core.RecommendedJavaVersion(version)
+
 	jarName := "server.jar"
 	if sType == core.TypeFabric {
 		// Install Fabric
-		launchJar, err := s.fabric.InstallFabric(serverPath, version, "") // loader="" means latest
+		// The installer runs on any Java, the one in PATH does when the recommended one is missing
+		installerJava, err := s.java.JavaPath(javaVersion)
+		if err != nil {
+			installerJava = "java"
+		}
+		launchJar, err := s.fabric.InstallFabric(installerJava, serverPath, version, "") // loader="" means latest
 		if err != nil {
 			os.RemoveAll(serverPath)
 			return nil, fmt.Errorf("fabric install failed: %w", err)
@@ -137,9 +276,33 @@ func (s *ServerService) CreateNewServer(name string, sType core.ServerType, port
 		Type:        sType,
 		Port:        port,
 		RAM:         ram,
-		JavaVersion: 21,
+		JavaVersion: javaVersion,
 		Version:     version,
 		JarName:     jarName,
+
+		RestartPolicy:     core.RestartNever,
+		RestartMaxRetries: 3,
+		RestartBackoff:    10,
+
+		StartupTimeout: 300,
+
+		StopTimeout: 30,
+		TermGrace:   10,
+
+		JVMArgs:    []string{},
+		ServerArgs: []string{},
+	}
+
+	// Imported worlds may ship their own server.properties, the panel's port wins
+	propsPath := filepath.Join(serverPath, "server.properties")
+	props, err := minecraft.LoadProperties(propsPath)
+	if err == nil {
+		props["server-port"] = strconv.Itoa(port)
+		err = minecraft.SaveProperties(propsPath, props)
+	}
+	if err != nil {
+		os.RemoveAll(serverPath)
+		return nil, fmt.Errorf("server.properties: %w", err)
 	}
 
 	// 5. Persistance
@@ -150,11 +313,20 @@ func (s *ServerService) CreateNewServer(name string, sType core.ServerType, port
 
 	// 6. Runtime
 	inst := s.manager.AddInstance(newID, serverPath, cfg.JarName)
-	inst.SetRAM(ram)
+	s.applyConfig(inst, cfg)
 
 	return cfg, nil
 }
 
+// SendRCON runs a command through RCON and returns the server's response
+func (s *ServerService) SendRCON(id string, cmd string) (string, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return "", fmt.Errorf("serveur introuvable")
+	}
+	return inst.RCON(cmd)
+}
+
 func (s *ServerService) StartServer(id string) error {
 	inst, exists := s.manager.GetInstance(id)
 	if !exists {
@@ -171,6 +343,26 @@ func (s *ServerService) StopServer(id string) error {
 	return inst.Stop()
 }
 
+// StopServerAndWait blocks until the process is gone, escalating to signals after timeout (0 = default)
+func (s *ServerService) StopServerAndWait(ctx context.Context, id string, timeout time.Duration) error {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return fmt.Errorf("serveur introuvable")
+	}
+	return inst.StopAndWait(ctx, timeout)
+}
+
+func (s *ServerService) RestartServer(ctx context.Context, id string, timeout time.Duration) error {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return fmt.Errorf("serveur introuvable")
+	}
+	if err := inst.StopAndWait(ctx, timeout); err != nil {
+		return fmt.Errorf("impossible d'arrêter le serveur: %w", err)
+	}
+	return inst.Start()
+}
+
 func (s *ServerService) SendCommand(id string, cmd string) error {
 	inst, exists := s.manager.GetInstance(id)
 	if !exists {
@@ -199,6 +391,22 @@ func (s *ServerService) GetServerLogHistory(id string) ([]string, error) {
 	return inst.GetHistory(), nil
 }
 
+// QueryConsole reads the on-disk console journal: the last `tail` lines when tail > 0, otherwise one page after q.Cursor
+func (s *ServerService) QueryConsole(id string, q minecraft.JournalQuery, tail int) (*minecraft.JournalPage, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return nil, fmt.Errorf("serveur introuvable")
+	}
+	if tail > 0 {
+		entries, err := inst.Journal().Tail(tail, q)
+		if err != nil {
+			return nil, err
+		}
+		return &minecraft.JournalPage{Entries: entries}, nil
+	}
+	return inst.Journal().Query(q)
+}
+
 func (s *ServerService) GetAllServers() ([]core.ServerConfig, error) {
 	return database.GetAllServers()
 }
@@ -212,9 +420,13 @@ func (s *ServerService) GetServerDetail(id string) (*core.ServerDetailResponse,
 
 	// 2. Runtime status
 	status := core.StatusStopped
+	restartCount := 0
+	var bootTime time.Duration
 	inst, exists := s.manager.GetInstance(id)
 	if exists {
 		status = inst.GetStatus()
+		restartCount = inst.GetRestartCount()
+		bootTime = inst.GetBootTime()
 	}
 
 	return &core.ServerDetailResponse{
@@ -226,9 +438,255 @@ func (s *ServerService) GetServerDetail(id string) (*core.ServerDetailResponse,
 		JavaVersion: cfg.JavaVersion,
 		Version:     cfg.Version,
 		Status:      status,
+
+		RestartPolicy: cfg.RestartPolicy,
+		RestartCount:  restartCount,
+		BootTime:      bootTime.Seconds(),
 	}, nil
 }
 
+// GetLiveStatus asks the running server itself (Server List Ping, plus query when enabled)
+// and refreshes the instance's player list with the answer.
+func (s *ServerService) GetLiveStatus(id string) (*minecraft.StatusReport, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return nil, fmt.Errorf("serveur introuvable")
+	}
+	if status := inst.GetStatus(); status != core.StatusRunning {
+		return &minecraft.StatusReport{
+			Sample:  []minecraft.PlayerSample{},
+			Plugins: []string{},
+			Sources: []string{},
+			Error:   fmt.Sprintf("server is %s", status),
+		}, nil
+	}
+
+	cfg, err := database.GetServer(id)
+	if err != nil {
+		return nil, err
+	}
+	props, err := minecraft.LoadProperties(filepath.Join(inst.RunDir, "server.properties"))
+	if err != nil {
+		return nil, err
+	}
+
+	gamePort := propInt(props, "server-port", cfg.Port)
+	queryPort := 0
+	if props["enable-query"] == "true" {
+		queryPort = propInt(props, "query.port", gamePort)
+	}
+
+	report := minecraft.Probe("127.0.0.1", gamePort, queryPort)
+	if players, ok := report.FullPlayerList(); ok {
+		inst.SyncPlayers(players)
+	}
+	return report, nil
+}
+
+// UpdateServer applies a partial settings update to the DB, server.properties and the live instance.
+// RAM, Java and jar changes take effect on the next start.
+func (s *ServerService) UpdateServer(id string, req *core.ServerUpdateRequest) (*core.ServerConfig, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return nil, fmt.Errorf("serveur introuvable")
+	}
+	cfg, err := database.GetServer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, fmt.Errorf("name cannot be empty")
+		}
+		cfg.Name = *req.Name
+	}
+	if req.Type != nil {
+		switch *req.Type {
+		case core.TypeVanilla, core.TypePaper, core.TypeFabric, core.TypeForge:
+			cfg.Type = *req.Type
+		default:
+			return nil, fmt.Errorf("invalid server type: %s", *req.Type)
+		}
+	}
+	portChanged := false
+	if req.Port != nil && *req.Port != cfg.Port {
+		if err := s.ports.CheckAvailable(*req.Port, id); err != nil {
+			return nil, err
+		}
+		cfg.Port = *req.Port
+		portChanged = true
+	}
+	if req.RAM != nil {
+		if *req.RAM < 512 {
+			return nil, fmt.Errorf("insufficient RAM (min 512MB)")
+		}
+		cfg.RAM = *req.RAM
+	}
+	var javaPath string
+	if req.JavaVersion != nil && *req.JavaVersion != cfg.JavaVersion {
+		rt, err := s.java.FindRuntime(*req.JavaVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfg.JavaVersion = *req.JavaVersion
+		javaPath = rt.Path
+	}
+	if req.JarName != nil {
+		if *req.JarName == "" || filepath.Base(*req.JarName) != *req.JarName || filepath.Ext(*req.JarName) != ".jar" {
+			return nil, fmt.Errorf("invalid jar name")
+		}
+		if _, err := os.Stat(filepath.Join(inst.RunDir, *req.JarName)); err != nil {
+			return nil, fmt.Errorf("jar %s not found in server directory", *req.JarName)
+		}
+		cfg.JarName = *req.JarName
+	}
+
+	if err := database.UpdateServer(cfg); err != nil {
+		return nil, err
+	}
+
+	if portChanged {
+		if err := s.UpdateProperties(id, map[string]string{"server-port": strconv.Itoa(cfg.Port)}); err != nil {
+			return nil, fmt.Errorf("failed to update server.properties: %w", err)
+		}
+	}
+
+	inst.SetRAM(cfg.RAM)
+	inst.SetJarName(cfg.JarName)
+	if javaPath != "" {
+		inst.SetJavaPath(javaPath)
+	}
+
+	return cfg, nil
+}
+
+func (s *ServerService) UpdateRestartPolicy(id string, policy core.RestartPolicy, maxRetries int, backoff int) error {
+	if !policy.IsValid() {
+		return fmt.Errorf("invalid restart policy: %s", policy)
+	}
+	if maxRetries < 0 || backoff < 0 {
+		return fmt.Errorf("max retries and backoff must not be negative")
+	}
+	if backoff == 0 {
+		backoff = 10
+	}
+
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return fmt.Errorf("serveur introuvable")
+	}
+
+	if err := database.UpdateRestartPolicy(id, policy, maxRetries, backoff); err != nil {
+		return err
+	}
+
+	inst.SetRestartPolicy(policy, maxRetries, time.Duration(backoff)*time.Second)
+	return nil
+}
+
+func (s *ServerService) UpdateStartupSettings(id string, readyPattern string, startupTimeout int) error {
+	if startupTimeout < 0 {
+		return fmt.Errorf("startup timeout must not be negative")
+	}
+	if startupTimeout == 0 {
+		startupTimeout = 300
+	}
+
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return fmt.Errorf("serveur introuvable")
+	}
+
+	// Validates the regex before persisting it
+	if err := inst.SetReadiness(readyPattern, time.Duration(startupTimeout)*time.Second); err != nil {
+		return err
+	}
+
+	return database.UpdateStartupSettings(id, readyPattern, startupTimeout)
+}
+
+// UpdateStopSettings changes how long a stop waits before escalating to SIGTERM, then SIGKILL
+func (s *ServerService) UpdateStopSettings(id string, stopTimeout, termGrace int) error {
+	if stopTimeout < 0 || termGrace < 0 {
+		return fmt.Errorf("stop timeout and term grace must not be negative")
+	}
+	if stopTimeout == 0 {
+		stopTimeout = int(minecraft.DefaultStopTimeout / time.Second)
+	}
+	if termGrace == 0 {
+		termGrace = int(minecraft.DefaultTermGrace / time.Second)
+	}
+
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return fmt.Errorf("serveur introuvable")
+	}
+
+	if err := database.UpdateStopSettings(id, stopTimeout, termGrace); err != nil {
+		return err
+	}
+	inst.SetStopTimeouts(time.Duration(stopTimeout)*time.Second, time.Duration(termGrace)*time.Second)
+	return nil
+}
+
+// UpdateLaunchArgs changes the JVM flags and server arguments, applied on the next start.
+// A preset name replaces jvmArgs with the preset's flags.
+func (s *ServerService) UpdateLaunchArgs(id string, preset string, jvmArgs, serverArgs []string) (*core.ServerConfig, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return nil, fmt.Errorf("serveur introuvable")
+	}
+
+	if preset != "" {
+		p, ok := minecraft.FindJVMPreset(preset)
+		if !ok {
+			return nil, fmt.Errorf("unknown preset: %s", preset)
+		}
+		jvmArgs = p.Args
+	}
+	if jvmArgs == nil {
+		jvmArgs = []string{}
+	}
+	if serverArgs == nil {
+		serverArgs = []string{}
+	}
+
+	if err := minecraft.ValidateJVMArgs(jvmArgs); err != nil {
+		return nil, err
+	}
+	if err := minecraft.ValidateServerArgs(serverArgs); err != nil {
+		return nil, err
+	}
+
+	if err := database.UpdateLaunchArgs(id, jvmArgs, serverArgs); err != nil {
+		return nil, err
+	}
+	inst.SetLaunchArgs(jvmArgs, serverArgs)
+
+	return database.GetServer(id)
+}
+
+// SetJavaVersion assigns the Java runtime a server runs with, used from its next start
+func (s *ServerService) SetJavaVersion(id string, major int) (*core.JavaRuntime, error) {
+	inst, exists := s.manager.GetInstance(id)
+	if !exists {
+		return nil, fmt.Errorf("serveur introuvable")
+	}
+
+	rt, err := s.java.FindRuntime(major)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.UpdateJavaVersion(id, major); err != nil {
+		return nil, err
+	}
+
+	inst.SetJavaPath(rt.Path)
+	return rt, nil
+}
+
 func (s *ServerService) GetProperties(id string) (map[string]string, error) {
 	inst, exists := s.manager.GetInstance(id)
 	if !exists {
@@ -253,13 +711,45 @@ func (s *ServerService) UpdateProperties(id string, newProps map[string]string)
 		return err
 	}
 
+	// Keep the DB port in sync when server-port is edited directly
+	var newPort int
+	if v, ok := newProps["server-port"]; ok && v != currentProps["server-port"] {
+		newPort, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid server-port: %s", v)
+		}
+		cfg, err := database.GetServer(id)
+		if err != nil {
+			return err
+		}
+		if cfg.Port != newPort {
+			if err := s.ports.CheckAvailable(newPort, id); err != nil {
+				return err
+			}
+		}
+	}
+
 	// 2. Fusionner (update existing keys or add new ones)
 	for k, v := range newProps {
 		currentProps[k] = v
 	}
 
 	// 3. Sauvegarder
-	return minecraft.SaveProperties(propsPath, currentProps)
+	if err := minecraft.SaveProperties(propsPath, currentProps); err != nil {
+		return err
+	}
+
+	if newPort != 0 {
+		cfg, err := database.GetServer(id)
+		if err != nil {
+			return err
+		}
+		if cfg.Port != newPort {
+			cfg.Port = newPort
+			return database.UpdateServer(cfg)
+		}
+	}
+	return nil
 }
 
 func (s *ServerService) ChangeServerVersion(id string, targetVersion string) error {
@@ -276,14 +766,9 @@ func (s *ServerService) ChangeServerVersion(id string, targetVersion string) err
 	}
 
 	if inst.GetStatus() != core.StatusStopped {
-		if err := inst.Stop(); err != nil {
+		if err := inst.StopAndWait(context.Background(), 0); err != nil {
 			return fmt.Errorf("impossible d'arrêter le serveur: %w", err)
 		}
-		// Wait for stop (simple sleep mostly, or check status loop)
-		// For simplicity, we assume Stop() blocks or we wait a bit,
-		// but Stop() in manager is async for process kill?
-		// Actually inst.Stop() sends command or kills.
-		// Ideally we should wait until status is stopped.
 	}
 
 	serverPath := inst.RunDir
@@ -303,22 +788,15 @@ func (s *ServerService) ChangeServerVersion(id string, targetVersion string) err
 	}
 
 	// 5. Update DB
-	// First get current config
 	cfg, err := database.GetServer(id)
 	if err != nil {
 		return err
 	}
-	// Delete old entry
-	if err := database.DeleteServer(id); err != nil {
-		return err
-	}
-	// Update version and recreate
 	cfg.Version = targetVersion
-	if err := database.CreateServer(cfg); err != nil {
-		return err
-	}
-
-	return nil
+	// The new version may need another Java, picked up by the next start
+	cfg.JavaVersion = core.RecommendedJavaVersion(targetVersion)
+	inst.SetTickMethod(minecraft.TickMethodFor(cfg.Type, cfg.Version))
+	return database.UpdateServer(cfg)
 }
 
 func (s *ServerService) GetVersions() ([]core.MojangVersion, error) {
@@ -326,14 +804,9 @@ func (s *ServerService) GetVersions() ([]core.MojangVersion, error) {
 }
 
 func (s *ServerService) DeleteServer(id string) error {
-	// 0. Stop memory instance
-	inst, exists := s.manager.GetInstance(id)
-	if exists {
-		// Stop if running (blocking or async? usually best effort)
-		if inst.GetStatus() != core.StatusStopped {
-			_ = inst.Stop()
-		}
-		s.manager.RemoveInstance(id)
+	// 0. Stop memory instance, files can't be removed under a running JVM
+	if err := s.manager.RemoveInstance(id); err != nil {
+		return fmt.Errorf("failed to stop server: %w", err)
 	}
 
 	// 1. Remove Files (Data)
@@ -356,7 +829,18 @@ func (s *ServerService) DeleteServer(id string) error {
 		return fmt.Errorf("failed to delete scheduled tasks: %w", err)
 	}
 
-	// 4. Remove DB Entry
+	// 4. Remove Metrics history, player sessions and world settings
+	if err := database.DeleteMetricsByServer(id); err != nil {
+		return fmt.Errorf("failed to delete metrics: %w", err)
+	}
+	if err := database.DeletePlayerSessionsByServer(id); err != nil {
+		return fmt.Errorf("failed to delete player sessions: %w", err)
+	}
+	if err := database.DeleteWorldSettingsByServer(id); err != nil {
+		return fmt.Errorf("failed to delete world settings: %w", err)
+	}
+
+	// 5. Remove DB Entry
 	if err := database.DeleteServer(id); err != nil {
 		return fmt.Errorf("failed to delete server from db: %w", err)
 	}
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/minecraft"
+)
+
+// Bukkit servers keep the nether and the end of <world> in <world>_nether/DIM-1 and
+// <world>_the_end/DIM1, vanilla and modded servers inside the world folder.
+var splitDimensions = []struct{ suffix, dim string }{
+	{"_nether", "DIM-1"},
+	{"_the_end", "DIM1"},
+}
+
+var invalidWorldChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+const (
+	// MaxWorldArchiveSize caps world uploads, the import route has the same body limit
+	MaxWorldArchiveSize int64 = 8 << 30
+	// Worlds compress well but not a thousandfold: more than this is a zip bomb
+	maxWorldExtractedSize int64 = 32 << 30
+)
+
+// WorldImport is the outcome of ImportWorld
+type WorldImport struct {
+	Name   string   `json:"name"`
+	Layout string   `json:"layout"` // singleplayer or split, as found in the archive
+	Dirs   []string `json:"dirs"`   // Directories created in the server
+}
+
+// ImportWorld extracts a world archive (.zip, .tar.gz or .tgz) into the server. The world is the
+// folder holding level.dat, at the root of the archive or a folder deep. Dimensions are moved to
+// where the server type expects them. name defaults to the world folder's.
+func (s *WorldService) ImportWorld(serverID, name string, fileHeader *multipart.FileHeader) (*WorldImport, error) {
+	if err := s.checkIdle(serverID); err != nil {
+		return nil, err
+	}
+	cfg, err := s.serverService.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	serverDir := filepath.Join(s.basePath, serverID)
+	workDir := filepath.Join(serverDir, minecraft.SupervisorDir)
+	if err := core.EnsureDir(workDir); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(workDir, "import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	extractDir := filepath.Join(tmpDir, "archive")
+	if err := extractUpload(fileHeader, extractDir); err != nil {
+		return nil, err
+	}
+
+	root, err := findWorldRoot(extractDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		base := filepath.Base(root)
+		if root == extractDir {
+			base = strings.TrimSuffix(strings.TrimSuffix(fileHeader.Filename, ".tgz"), ".tar.gz")
+			base = strings.TrimSuffix(base, ".zip")
+		}
+		name = invalidWorldChars.ReplaceAllString(base, "_")
+	}
+	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(name) {
+		return nil, fmt.Errorf("invalid world name: only alphanumeric, dashes and underscores allowed")
+	}
+
+	result := &WorldImport{Name: name, Layout: "singleplayer"}
+	split := cfg.Type == core.TypePaper
+
+	// Lay the dimensions out in the extracted tree first, so the final moves can't half fail
+	targets := []string{name}
+	sources := map[string]string{name: root}
+	for _, d := range splitDimensions {
+		inWorld := filepath.Join(root, d.dim)
+		sibling := ""
+		if root != extractDir && isDir(root+d.suffix) {
+			sibling = root + d.suffix
+			result.Layout = "split"
+		}
+
+		switch {
+		case split && sibling == "" && isDir(inWorld):
+			sibling = filepath.Join(tmpDir, "split"+d.suffix)
+			if err := os.MkdirAll(sibling, 0755); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(inWorld, filepath.Join(sibling, d.dim)); err != nil {
+				return nil, err
+			}
+		case !split && sibling != "":
+			if isDir(filepath.Join(sibling, d.dim)) && !isDir(inWorld) {
+				if err := os.Rename(filepath.Join(sibling, d.dim), inWorld); err != nil {
+					return nil, err
+				}
+			}
+			sibling = ""
+		}
+		if sibling != "" {
+			targets = append(targets, name+d.suffix)
+			sources[name+d.suffix] = sibling
+		}
+	}
+
+	for _, target := range targets {
+		if _, err := os.Stat(filepath.Join(serverDir, target)); err == nil {
+			return nil, fmt.Errorf("world %s already exists", target)
+		}
+	}
+	for _, target := range targets {
+		if err := os.Rename(sources[target], filepath.Join(serverDir, target)); err != nil {
+			return nil, err
+		}
+		result.Dirs = append(result.Dirs, target)
+	}
+	return result, nil
+}
+
+// extractUpload copies an uploaded archive to disk, zip needs random access, then extracts it
+func extractUpload(fileHeader *multipart.FileHeader, dest string) error {
+	lower := strings.ToLower(fileHeader.Filename)
+	var extract func(string, string) error
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		extract = extractWorldTarGz
+	case strings.HasSuffix(lower, ".zip"):
+		extract = extractWorldZip
+	default:
+		return fmt.Errorf("unsupported archive format, allowed: .zip, .tar.gz, .tgz")
+	}
+	if fileHeader.Size > MaxWorldArchiveSize {
+		return fmt.Errorf("archive is larger than %d GB", MaxWorldArchiveSize>>30)
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, io.LimitReader(src, MaxWorldArchiveSize))
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	if err := extract(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("extraction failed: %w", err)
+	}
+	return nil
+}
+
+// Unlike the JDK extractors, the world ones only create directories and regular files: worlds
+// never hold links, and a link would let later entries be written outside dest.
+
+func extractWorldTarGz(archivePath, dest string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	budget := maxWorldExtractedSize
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeWorldFile(path, tr, &budget); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %s: only files and directories are allowed", header.Name)
+		}
+	}
+}
+
+func extractWorldZip(archivePath, dest string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	budget := maxWorldExtractedSize
+	for _, f := range r.File {
+		path, err := safeJoin(dest, f.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeWorldFile(path, rc, &budget)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %s: only files and directories are allowed", f.Name)
+		}
+	}
+	return nil
+}
+
+// writeWorldFile copies an entry, taking its size from budget. Headers can lie about sizes,
+// so what is actually written counts.
+func writeWorldFile(path string, r io.Reader, budget *int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, *budget+1))
+	out.Close()
+	if err != nil {
+		return err
+	}
+	*budget -= n
+	if *budget < 0 {
+		return fmt.Errorf("archive expands to more than %d GB", maxWorldExtractedSize>>30)
+	}
+	return nil
+}
+
+// findWorldRoot returns the folder holding level.dat, at most two levels below dir. Bukkit's
+// <world>_nether and <world>_the_end have one as well and are skipped when <world> is there.
+func findWorldRoot(dir string) (string, error) {
+	var roots []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if isFile(filepath.Join(path, "level.dat")) {
+			roots = append(roots, path)
+			return filepath.SkipDir
+		}
+		if rel, _ := filepath.Rel(dir, path); strings.Count(rel, string(os.PathSeparator)) >= 1 {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	worlds := []string{}
+	for _, root := range roots {
+		dimension := false
+		for _, d := range splitDimensions {
+			if base := strings.TrimSuffix(root, d.suffix); base != root && isFile(filepath.Join(base, "level.dat")) {
+				dimension = true
+			}
+		}
+		if !dimension {
+			worlds = append(worlds, root)
+		}
+	}
+
+	switch len(worlds) {
+	case 0:
+		return "", fmt.Errorf("no level.dat found in archive")
+	case 1:
+		return worlds[0], nil
+	default:
+		names := make([]string, len(worlds))
+		for idx, w := range worlds {
+			names[idx] = filepath.Base(w)
+		}
+		return "", fmt.Errorf("archive contains several worlds: %s", strings.Join(names, ", "))
+	}
+}
+
+// WorldArchive is a world and its split dimension folders, ready to be streamed
+type WorldArchive struct {
+	Name      string
+	serverDir string
+	dirs      []string
+}
+
+// ExportWorld prepares the archive of a world, with <name>_nether and <name>_the_end when the
+// server keeps them apart.
+func (s *WorldService) ExportWorld(serverID, name string) (*WorldArchive, error) {
+	if name != filepath.Base(name) || name == ".." {
+		return nil, fmt.Errorf("invalid world name")
+	}
+	serverDir := filepath.Join(s.basePath, serverID)
+	if !isDir(filepath.Join(serverDir, name)) {
+		return nil, fmt.Errorf("world not found")
+	}
+
+	archive := &WorldArchive{Name: name, serverDir: serverDir, dirs: []string{name}}
+	for _, d := range splitDimensions {
+		if isDir(filepath.Join(serverDir, name+d.suffix)) {
+			archive.dirs = append(archive.dirs, name+d.suffix)
+		}
+	}
+	return archive, nil
+}
+
+// WriteZip streams the world as a zip
+func (a *WorldArchive) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	err := a.walk(func(rel string, info fs.FileInfo, file *os.File) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		writer, err := archive.CreateHeader(header)
+		if err != nil || file == nil {
+			return err
+		}
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// WriteTarGz streams the world as a gzipped tarball
+func (a *WorldArchive) WriteTarGz(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	err := a.walk(func(rel string, info fs.FileInfo, file *os.File) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := archive.WriteHeader(header); err != nil || file == nil {
+			return err
+		}
+		_, err = io.Copy(archive, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// walk calls add for every directory and regular file of the world, files are opened for it.
+// session.lock is left out like in backups.
+func (a *WorldArchive) walk(add func(rel string, info fs.FileInfo, file *os.File) error) error {
+	for _, dir := range a.dirs {
+		err := filepath.Walk(filepath.Join(a.serverDir, dir), func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Name() == "session.lock" || !(info.IsDir() || info.Mode().IsRegular()) {
+				return nil
+			}
+			rel, err := filepath.Rel(a.serverDir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if info.IsDir() {
+				return add(rel, info, nil)
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return add(rel, info, file)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -1,27 +1,53 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZiplEix/crafteur/core"
+	"github.com/ZiplEix/crafteur/database"
+	"github.com/ZiplEix/crafteur/minecraft"
 )
 
 type WorldEntry struct {
 	Name     string `json:"name"`
 	IsActive bool   `json:"is_active"`
 	Size     int64  `json:"size"`
+
+	// Valid is false when level.dat is missing or unreadable: a world that was never generated,
+	// or a directory that isn't a world at all.
+	Valid bool                 `json:"valid"`
+	Error string               `json:"error,omitempty"`
+	Level *minecraft.LevelInfo `json:"level,omitempty"`
+
+	Settings   *core.WorldSettings `json:"settings,omitempty"` // Set for worlds created from the panel
+	Generating bool                `json:"generating"`
 }
 
+// worldProperties are the server.properties keys a world's settings are applied to
+var worldProperties = []string{"level-name", "level-seed", "level-type", "generator-settings", "generate-structures", "hardcore"}
+
 type WorldService struct {
 	serverService *ServerService
 	basePath      string
+
+	mu         sync.Mutex
+	generating map[string]string // Server ID -> world being pre-generated
 }
 
 func NewWorldService(serverService *ServerService, basePath string) *WorldService {
 	return &WorldService{
 		serverService: serverService,
 		basePath:      basePath,
+		generating:    make(map[string]string),
 	}
 }
 
@@ -33,6 +59,10 @@ func (s *WorldService) ListWorlds(serverID string) ([]WorldEntry, error) {
 	}
 	currentLevel := props["level-name"]
 
+	s.mu.Lock()
+	generating := s.generating[serverID]
+	s.mu.Unlock()
+
 	// 2. Scan server directory
 	serverDir := filepath.Join(s.basePath, serverID)
 	entries, err := os.ReadDir(serverDir)
@@ -60,6 +90,7 @@ func (s *WorldService) ListWorlds(serverID string) ([]WorldEntry, error) {
 		".git":           true,
 		".idea":          true,
 		".vscode":        true,
+		".crafteur":      true,  // Supervisor runtime files
 		"lobby":          false, // Lobby is a world
 	}
 
@@ -79,37 +110,89 @@ func (s *WorldService) ListWorlds(serverID string) ([]WorldEntry, error) {
 		// This allows empty/new worlds to be listed and activated.
 		size, _ := getDirSize(worldPath)
 
-		worlds = append(worlds, WorldEntry{
-			Name:     worldName,
-			IsActive: worldName == currentLevel,
-			Size:     size,
-		})
+		world := WorldEntry{
+			Name:       worldName,
+			IsActive:   worldName == currentLevel,
+			Size:       size,
+			Generating: worldName == generating,
+		}
+		if settings, err := database.GetWorldSettings(serverID, worldName); err == nil {
+			world.Settings = settings
+		}
+		if level, err := minecraft.ReadLevel(worldPath); err != nil {
+			world.Error = levelError(err)
+		} else {
+			world.Valid = true
+			world.Level = level
+		}
+		worlds = append(worlds, world)
 	}
 
 	return worlds, nil
 }
 
-func (s *WorldService) CreateWorld(serverID, name string) error {
+// CreateWorld makes the directory of a new world and stores its generation settings. The world
+// is generated on its first start, right away with pregenerate: the server is started with it and
+// stopped once spawn is ready.
+func (s *WorldService) CreateWorld(serverID, name string, settings core.WorldSettings, pregenerate bool) error {
 	// Validate name (alphanumeric, dashes, underscores)
 	validName := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 	if !validName.MatchString(name) {
 		return fmt.Errorf("invalid world name: only alphanumeric, dashes and underscores allowed")
 	}
+	if settings.LevelType == "" {
+		settings.LevelType = "normal"
+	}
+	if !slices.Contains(minecraft.LevelTypes, settings.LevelType) {
+		return fmt.Errorf("invalid level type %q", settings.LevelType)
+	}
+	if settings.GeneratorSettings != "" && !json.Valid([]byte(settings.GeneratorSettings)) {
+		return fmt.Errorf("generator settings must be JSON")
+	}
+
+	var inst *minecraft.Instance
+	if pregenerate {
+		var ok bool
+		if inst, ok = s.serverService.manager.GetInstance(serverID); !ok {
+			return fmt.Errorf("serveur introuvable")
+		}
+		if status := inst.GetStatus(); status != core.StatusStopped && status != core.StatusCrashed {
+			return fmt.Errorf("stop the server to pre-generate a world")
+		}
+		if err := s.lock(serverID, name); err != nil {
+			return err
+		}
+	}
 
 	serverDir := filepath.Join(s.basePath, serverID)
 	worldPath := filepath.Join(serverDir, name)
 
-	if err := os.Mkdir(worldPath, 0755); err != nil {
-		if os.IsExist(err) {
-			return fmt.Errorf("world already exists")
-		}
-		return err
+	err := os.Mkdir(worldPath, 0755)
+	if os.IsExist(err) {
+		err = fmt.Errorf("world already exists")
+	}
+	if err == nil {
+		err = database.SaveWorldSettings(serverID, name, &settings)
 	}
 
-	return nil
+	if pregenerate {
+		if err != nil {
+			s.unlock(serverID)
+			return err
+		}
+		go s.pregenerate(inst, serverID, name)
+	}
+	return err
 }
 
-func (s *WorldService) ActivateWorld(serverID, worldName string) error {
+// ActivateWorld makes worldName the level loaded on next start, with the server.properties keys
+// of its settings. The returned warning is set when the world was saved by a newer game version
+// than the server's, which can't load it safely.
+func (s *WorldService) ActivateWorld(serverID, worldName string) (string, error) {
+	if err := s.checkIdle(serverID); err != nil {
+		return "", err
+	}
+
 	serverDir := filepath.Join(s.basePath, serverID)
 	worldPath := filepath.Join(serverDir, worldName)
 
@@ -117,16 +200,29 @@ func (s *WorldService) ActivateWorld(serverID, worldName string) error {
 	// The prompt implies checking format validity, but strictly speaking just checking Dir existence + maybe logic from ListWorlds
 	// For Activate, we trust it's a valid target if it exists.
 	if _, err := os.Stat(worldPath); os.IsNotExist(err) {
-		return fmt.Errorf("world not found")
+		return "", fmt.Errorf("world not found")
 	}
 
-	// Update level-name
-	return s.serverService.UpdateProperties(serverID, map[string]string{
-		"level-name": worldName,
-	})
+	var warning string
+	if level, err := minecraft.ReadLevel(worldPath); err == nil && level.DataVersion > 0 {
+		if serverVersion, name := s.serverDataVersion(serverID); serverVersion > 0 && level.DataVersion > serverVersion {
+			warning = fmt.Sprintf("World was saved by %s (data version %d), newer than the server's %s (data version %d): it may fail to load or lose data",
+				level.VersionName, level.DataVersion, name, serverVersion)
+		}
+	}
+
+	props, err := s.worldProps(serverID, worldName)
+	if err != nil {
+		return "", err
+	}
+	return warning, s.serverService.UpdateProperties(serverID, props)
 }
 
 func (s *WorldService) DeleteWorld(serverID, worldName string) error {
+	if err := s.checkIdle(serverID); err != nil {
+		return err
+	}
+
 	// 1. Check if active
 	props, err := s.serverService.GetProperties(serverID)
 	if err != nil {
@@ -140,7 +236,161 @@ func (s *WorldService) DeleteWorld(serverID, worldName string) error {
 	serverDir := filepath.Join(s.basePath, serverID)
 	worldPath := filepath.Join(serverDir, worldName)
 
-	return os.RemoveAll(worldPath)
+	if err := os.RemoveAll(worldPath); err != nil {
+		return err
+	}
+	return database.DeleteWorldSettings(serverID, worldName)
+}
+
+// worldProps returns the server.properties keys selecting a world. Worlds that weren't created
+// from the panel keep the current generation keys, unless they come from the active world's
+// settings: those are reset to the defaults so they don't leak to the next world.
+func (s *WorldService) worldProps(serverID, worldName string) (map[string]string, error) {
+	props := map[string]string{"level-name": worldName}
+
+	settings, err := database.GetWorldSettings(serverID, worldName)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		current, err := s.serverService.GetProperties(serverID)
+		if err != nil {
+			return nil, err
+		}
+		active, err := database.GetWorldSettings(serverID, current["level-name"])
+		if err != nil || active == nil {
+			return props, err
+		}
+		settings = &core.WorldSettings{LevelType: "normal", GenerateStructures: true}
+	}
+	cfg, err := s.serverService.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	generatorSettings := settings.GeneratorSettings
+	if generatorSettings == "" {
+		generatorSettings = "{}"
+	}
+	props["level-seed"] = settings.Seed
+	props["level-type"] = minecraft.LevelTypeProperty(settings.LevelType, cfg.Version)
+	props["generator-settings"] = generatorSettings
+	props["generate-structures"] = strconv.FormatBool(settings.GenerateStructures)
+	props["hardcore"] = strconv.FormatBool(settings.Hardcore)
+	return props, nil
+}
+
+// pregenerate starts the server on worldName until it is ready, then stops it and puts the
+// previous world back.
+func (s *WorldService) pregenerate(inst *minecraft.Instance, serverID, worldName string) {
+	defer s.unlock(serverID)
+
+	previous, err := s.serverService.GetProperties(serverID)
+	if err != nil {
+		fmt.Printf("Erreur pré-génération %s/%s: %v\n", serverID, worldName, err)
+		return
+	}
+	restore := map[string]string{}
+	for _, key := range worldProperties {
+		restore[key] = previous[key]
+	}
+	defer func() {
+		if err := s.serverService.UpdateProperties(serverID, restore); err != nil {
+			fmt.Printf("Erreur restauration server.properties %s: %v\n", serverID, err)
+		}
+	}()
+
+	props, err := s.worldProps(serverID, worldName)
+	if err == nil {
+		err = s.serverService.UpdateProperties(serverID, props)
+	}
+	if err == nil {
+		err = inst.Start()
+	}
+	if err != nil {
+		fmt.Printf("Erreur pré-génération %s/%s: %v\n", serverID, worldName, err)
+		return
+	}
+
+	// The startup timeout of the instance turns a stuck boot into a crash, this only bounds the wait
+	timeout := 30 * time.Minute
+	if cfg, err := s.serverService.GetServer(serverID); err == nil && cfg.StartupTimeout > 0 {
+		timeout = time.Duration(cfg.StartupTimeout)*time.Second + time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+wait:
+	for inst.GetStatus() == core.StatusStarting {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-ticker.C:
+		}
+	}
+	if status := inst.GetStatus(); status != core.StatusRunning {
+		fmt.Printf("Pré-génération %s/%s: le serveur n'a pas démarré (%s)\n", serverID, worldName, status)
+	}
+	if err := inst.StopAndWait(context.Background(), 0); err != nil {
+		fmt.Printf("Erreur arrêt après pré-génération %s: %v\n", serverID, err)
+	}
+}
+
+func (s *WorldService) lock(serverID, worldName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if world, busy := s.generating[serverID]; busy {
+		return fmt.Errorf("world %s is being pre-generated", world)
+	}
+	s.generating[serverID] = worldName
+	return nil
+}
+
+func (s *WorldService) unlock(serverID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.generating, serverID)
+}
+
+// checkIdle refuses world changes while a pre-generation owns server.properties
+func (s *WorldService) checkIdle(serverID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if world, busy := s.generating[serverID]; busy {
+		return fmt.Errorf("world %s is being pre-generated", world)
+	}
+	return nil
+}
+
+// serverDataVersion returns the world DataVersion of the server's game version, 0 when unknown.
+// Paper and Forge keep the vanilla jar in cache/ or versions/, Fabric next to its launcher.
+func (s *WorldService) serverDataVersion(serverID string) (int, string) {
+	serverDir := filepath.Join(s.basePath, serverID)
+	candidates := []string{}
+	if cfg, err := s.serverService.GetServer(serverID); err == nil && cfg.JarName != "" {
+		candidates = append(candidates, filepath.Join(serverDir, cfg.JarName))
+	}
+	candidates = append(candidates, filepath.Join(serverDir, "server.jar"))
+	for _, pattern := range []string{"cache/mojang_*.jar", "versions/*/*.jar"} {
+		matches, _ := filepath.Glob(filepath.Join(serverDir, pattern))
+		candidates = append(candidates, matches...)
+	}
+
+	for _, jar := range candidates {
+		if version, name, err := minecraft.JarDataVersion(jar); err == nil {
+			return version, name
+		}
+	}
+	return 0, ""
+}
+
+func levelError(err error) string {
+	if os.IsNotExist(err) {
+		return "no level.dat (world not generated yet)"
+	}
+	return fmt.Sprintf("invalid level.dat: %v", err)
 }
 
 func getDirSize(path string) (int64, error) {
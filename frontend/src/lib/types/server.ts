@@ -2,11 +2,36 @@ export interface ServerStats {
     cpu: number;
     ram: number;
     ram_max: number;
+    threads: number;
+    tick?: TickStats;
 }
 
-export type WSMessageType = 'log' | 'status' | 'stats';
+export interface TickStats {
+    tps: number;
+    tps_5m?: number;
+    tps_15m?: number;
+    mspt: number;
+    mspt_min?: number;
+    mspt_max?: number;
+    source: 'paper' | 'forge' | 'vanilla';
+    time: string;
+}
+
+export type WSMessageType = 'log' | 'status' | 'stats' | 'crash' | 'ack' | 'error' | 'pong';
 
 export interface WSMessage {
     type: WSMessageType;
     data: any;
+    cursor?: string;
+}
+
+// Console line in structured mode (?format=structured)
+export interface LogLine {
+    time?: string;
+    thread?: string;
+    level?: 'TRACE' | 'DEBUG' | 'INFO' | 'WARN' | 'ERROR' | 'FATAL';
+    logger?: string;
+    message: string;
+    raw: string;
+    continuation?: boolean;
 }